@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/note/testnaka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-accounts", "4", "-scenario", "repayment,backdate,early_payoff,clear_flat_rate_pending", "-date", "2025-01-15"}, &out, &out)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "{\n  \"rs_body\": [\n    {\n      \"transaction_date\": \"2025-01-15\",\n"))
+
+	body, err := testnaka.DecodeBody(&out)
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 4*3+3)
+	assert.Empty(t, testnaka.CheckBodyConsistency(body, testnaka.Options{}).Findings)
+}
+
+func TestRun_file(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fixture.json")
+	var out bytes.Buffer
+	assert.NoError(t, run([]string{"-accounts", "2", "-bills", "1", "-compact", "-out", file}, &out, &out))
+	assert.Empty(t, out.String())
+
+	raw, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(raw), "\n"))
+	body, err := testnaka.DecodeBody(bytes.NewReader(raw))
+	assert.NoError(t, err)
+	// a single bill is due today, no penalty or fee
+	assert.Len(t, body.ReqBody, 2)
+}
+
+func TestRun_badFlags(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"-scenario", "write_off"}, &out, &out))
+	assert.Error(t, run([]string{"-date", "15/01/2025"}, &out, &out))
+	assert.Error(t, run([]string{"-accounts", "0"}, &out, &out))
+}
@@ -0,0 +1,224 @@
+// Command dloan-mock serves recorded publishMessageDetail responses as a
+// local stand-in for the query-dloan-payment service.
+//
+//	dloan-mock -addr :8080 -fixture query-dloan-payment-publishMessageDetail_response.json -latency 200ms -error-rate 0.1
+//
+// Requests take the query parameters of the client package: account_number,
+// transaction_date_from, transaction_date_to, job_id and event_code filter,
+// page and page_size page. mock_latency (a duration) and mock_status (an HTTP
+// status) override the injected latency and error of one request.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/note/testnaka"
+	"github.com/note/testnaka/client"
+)
+
+// Query parameters that inject latency and errors into one request
+const (
+	paramLatency = "mock_latency"
+	paramStatus  = "mock_status"
+)
+
+// listFlag collects a flag that may be repeated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := run(ctx, os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "dloan-mock:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("dloan-mock", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		addr, path string
+		fixtures   listFlag
+		cfg        faultConfig
+		seed       int64
+	)
+	fs.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	fs.StringVar(&path, "path", client.DefaultPath, "path of the publishMessageDetail query")
+	fs.Var(&fixtures, "fixture", "recorded response to serve (repeatable, rs_body arrays are concatenated in order)")
+	fs.DurationVar(&cfg.Latency, "latency", 0, "delay before every response")
+	fs.DurationVar(&cfg.Jitter, "jitter", 0, "random extra delay up to this duration")
+	fs.Float64Var(&cfg.ErrorRate, "error-rate", 0, "fraction of requests answered with -error-status, 0 to 1")
+	fs.IntVar(&cfg.ErrorStatus, "error-status", http.StatusServiceUnavailable, "status of injected errors")
+	fs.Int64Var(&seed, "seed", 0, "seed of the latency and error randomness, 0 for the current time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		fixtures = listFlag{testnaka.ResponseFile}
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		return fmt.Errorf("invalid -error-rate %v: want 0 to 1", cfg.ErrorRate)
+	}
+	if cfg.ErrorStatus < 100 || cfg.ErrorStatus > 599 {
+		return fmt.Errorf("invalid -error-status %d: want 100 to 599", cfg.ErrorStatus)
+	}
+	body, err := loadFixtures(fixtures)
+	if err != nil {
+		return err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	logger := log.New(stderr, "dloan-mock: ", log.LstdFlags)
+	mux := http.NewServeMux()
+	mux.Handle(path, newServer(body, cfg, rand.New(rand.NewSource(seed)), logger))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	logger.Printf("serving %d transactions on http://%s%s", len(body.ReqBody), ln.Addr(), path)
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loadFixtures reads the recorded responses into one body
+func loadFixtures(files []string) (testnaka.Body, error) {
+	var body testnaka.Body
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return body, err
+		}
+		b, err := testnaka.DecodeBody(file)
+		file.Close()
+		if err != nil {
+			return body, fmt.Errorf("%s: %w", name, err)
+		}
+		body.ReqBody = append(body.ReqBody, b.ReqBody...)
+	}
+	return body, nil
+}
+
+// faultConfig is the latency and errors injected into every request
+type faultConfig struct {
+	Latency     time.Duration
+	Jitter      time.Duration
+	ErrorRate   float64
+	ErrorStatus int
+}
+
+// server answers publishMessageDetail queries from a recorded body
+type server struct {
+	body   testnaka.Body
+	cfg    faultConfig
+	logger *log.Logger
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newServer(body testnaka.Body, cfg faultConfig, rnd *rand.Rand, logger *log.Logger) *server {
+	return &server{body: body, cfg: cfg, rand: rnd, logger: logger}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	latency, status, err := s.faults(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+	if status != 0 {
+		s.logger.Printf("%s -> injected %d", r.URL.RawQuery, status)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	req, err := client.ParseRequest(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out := testnaka.Body{ReqBody: req.Select(s.body.ReqBody)}
+	s.logger.Printf("%s -> %d transactions", r.URL.RawQuery, len(out.ReqBody))
+	w.Header().Set("Content-Type", "application/json")
+	if err := testnaka.EncodeBody(w, out); err != nil {
+		s.logger.Printf("write response: %v", err)
+	}
+}
+
+// faults returns the latency and the error status, 0 for none, of a request
+func (s *server) faults(q url.Values) (time.Duration, int, error) {
+	s.mu.Lock()
+	latency := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		latency += time.Duration(s.rand.Int63n(int64(s.cfg.Jitter)))
+	}
+	var status int
+	if s.cfg.ErrorRate > 0 && s.rand.Float64() < s.cfg.ErrorRate {
+		status = s.cfg.ErrorStatus
+	}
+	s.mu.Unlock()
+
+	if v := q.Get(paramLatency); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %w", paramLatency, v, err)
+		}
+		latency = d
+	}
+	if v := q.Get(paramStatus); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 100 || n > 599 {
+			return 0, 0, fmt.Errorf("invalid %s %q", paramStatus, v)
+		}
+		status = n
+		if n == http.StatusOK {
+			status = 0
+		}
+	}
+	return latency, status, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka/client"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixture = "../../testnaka/query-dloan-payment-publishMessageDetail_response.json"
+
+func newTestServer(t *testing.T, cfg faultConfig) *httptest.Server {
+	body, err := loadFixtures([]string{fixture})
+	assert.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.Handle(client.DefaultPath, newServer(body, cfg, rand.New(rand.NewSource(1)), log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServer_replay(t *testing.T) {
+	srv := newTestServer(t, faultConfig{})
+	c := client.New(client.Config{BaseURL: srv.URL, PageSize: 100})
+
+	body, err := c.Fetch(context.Background(), client.Query{})
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 258)
+}
+
+func TestServer_filters(t *testing.T) {
+	srv := newTestServer(t, faultConfig{})
+	c := client.New(client.Config{BaseURL: srv.URL})
+	day, _ := null.NewDates("2025-01-15")
+
+	body, err := c.Fetch(context.Background(), client.Query{
+		AccountNumbers: []int64{190000003836},
+		EventCodes:     []string{"due_bills"},
+		DateFrom:       day,
+		DateTo:         day,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, body.ReqBody)
+	for _, tx := range body.ReqBody {
+		assert.Equal(t, int64(190000003836), tx.AccountNumber.Val)
+		assert.Equal(t, "due_bills", tx.EventCode.String())
+	}
+
+	job := body.ReqBody[0].JobID.String()
+	body, err = c.Fetch(context.Background(), client.Query{JobIDs: []string{job}})
+	assert.NoError(t, err)
+	for _, tx := range body.ReqBody {
+		assert.Equal(t, job, tx.JobID.String())
+	}
+
+	next, _ := null.NewDates("2025-01-16")
+	body, err = c.Fetch(context.Background(), client.Query{DateFrom: next})
+	assert.NoError(t, err)
+	assert.Empty(t, body.ReqBody)
+}
+
+func TestServer_badQuery(t *testing.T) {
+	srv := newTestServer(t, faultConfig{})
+	for _, q := range []string{"account_number=x", "transaction_date_from=15/01/2025", "page_size=-1", "page=0&page_size=10", "page=0", "page=4611686018427387904&page_size=4", "mock_status=42", "mock_latency=soon"} {
+		resp, err := http.Get(srv.URL + client.DefaultPath + "?" + q)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, q)
+	}
+}
+
+func TestServer_injectedErrors(t *testing.T) {
+	srv := newTestServer(t, faultConfig{ErrorRate: 1, ErrorStatus: http.StatusBadGateway})
+	c := client.New(client.Config{BaseURL: srv.URL, MaxRetries: 1, Backoff: time.Millisecond})
+
+	_, err := c.Fetch(context.Background(), client.Query{})
+	var status *client.StatusError
+	if assert.True(t, errors.As(err, &status)) {
+		assert.Equal(t, http.StatusBadGateway, status.StatusCode)
+	}
+
+	// mock_status=200 turns the injected error off for one request
+	resp, err := http.Get(srv.URL + client.DefaultPath + "?mock_status=200")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_perRequestFaults(t *testing.T) {
+	srv := newTestServer(t, faultConfig{})
+
+	resp, err := http.Get(srv.URL + client.DefaultPath + "?mock_status=500")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	start := time.Now()
+	resp, err = http.Get(srv.URL + client.DefaultPath + "?mock_latency=50ms&account_number=1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestServer_latencyTimeout(t *testing.T) {
+	srv := newTestServer(t, faultConfig{Latency: time.Second})
+	c := client.New(client.Config{BaseURL: srv.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Fetch(ctx, client.Query{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRun_stops(t *testing.T) {
+	var errOut bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := run(ctx, []string{"-addr", "127.0.0.1:0", "-fixture", fixture}, &errOut)
+	assert.NoError(t, err)
+	assert.Contains(t, errOut.String(), "serving 258 transactions on http://127.0.0.1:")
+}
+
+func TestRun_badFlags(t *testing.T) {
+	var errOut bytes.Buffer
+	assert.Error(t, run(context.Background(), []string{"-error-rate", "2", "-fixture", fixture}, &errOut))
+	assert.Error(t, run(context.Background(), []string{"-fixture", "missing.json"}, &errOut))
+	assert.Error(t, run(context.Background(), []string{"-error-status", "42", "-fixture", fixture}, &errOut))
+}
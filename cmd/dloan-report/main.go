@@ -0,0 +1,563 @@
+// Command dloan-report extracts the payment report from a
+// query-dloan-payment publishMessageDetail response.
+//
+//	dloan-report -in response.json -out report.txt -account 190000003836 -event due_bills
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka"
+)
+
+// listFlag collects a flag that may be repeated or given as a comma separated list
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "dloan-report:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("dloan-report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		in, out, mode      string
+		after              string
+		format, group      string
+		bom                bool
+		from, to           string
+		asOf, priorAsOf    string
+		stage2, stage3     int64
+		rulesFile          string
+		keepBillGeneration bool
+		keepUnknown        bool
+		validate           bool
+		strict             string
+		workers            int
+		maskKeyFile, vault string
+		accounts, events   listFlag
+		entries            listFlag
+		channels, prefixes listFlag
+	)
+	fs.StringVar(&in, "in", "-", "response file, - for stdin")
+	fs.StringVar(&out, "out", "-", "report file, - for stdout")
+	fs.StringVar(&mode, "mode", "report", "report to write: report (one row per transaction), bills (one row per bill, penalty and fee), summary (totals), consistency (message amounts that differ from their nested bills), payoff (early payoff components), timeline (events per account with running paid totals), backdate (adjustments paired with their postings), awaiting (accounts awaiting back-date), ids (chrono_sequence and job_id format and order issues), entries (totals per entry point), aging (days past due per account), aging-buckets (accounts and unpaid amounts per aging bucket), stages (IFRS 9 stage per account), stage-migration (accounts moving between stages from -prior-as-of to -as-of), diff (changes from -in to -after), mask (the response with identifiers masked) or unmask (a masked response restored from -vault)")
+	fs.StringVar(&after, "after", "", "with -mode diff, the rerun response to compare -in with")
+	fs.StringVar(&group, "group", "account-date", "with -mode summary, total by account, date or account-date")
+	fs.StringVar(&format, "format", "pipe", "output format: pipe, csv, tsv, jsonl, fixed or markdown")
+	fs.BoolVar(&bom, "bom", false, "start csv output with a UTF-8 byte order mark")
+	fs.Var(&accounts, "account", "account number to keep (repeatable, comma separated)")
+	fs.Var(&events, "event", "event code to keep (repeatable, comma separated)")
+	fs.StringVar(&from, "from", "", "first transaction date to keep, yyyy-mm-dd")
+	fs.StringVar(&to, "to", "", "last transaction date to keep, yyyy-mm-dd")
+	fs.StringVar(&asOf, "as-of", "", "with -mode awaiting, aging, aging-buckets, stages or stage-migration, the date ages are counted to, yyyy-mm-dd, today by default")
+	fs.StringVar(&priorAsOf, "prior-as-of", "", "with -mode stage-migration, the earlier date stages are compared from, yyyy-mm-dd")
+	fs.Int64Var(&stage2, "stage2-days", testnaka.DefaultStagingThresholds().Stage2Days, "with -mode stages or stage-migration, days past due beyond which an account is stage 2")
+	fs.Int64Var(&stage3, "stage3-days", testnaka.DefaultStagingThresholds().Stage3Days, "with -mode stages or stage-migration, days past due beyond which an account is stage 3")
+	fs.Var(&entries, "entry", "last_updated_description to keep (repeatable)")
+	fs.Var(&channels, "channel", "entry point channel to keep, e.g. REST or KAFKA (repeatable, comma separated)")
+	fs.Var(&prefixes, "path-prefix", "entry point path prefix to keep (repeatable, comma separated)")
+	fs.StringVar(&rulesFile, "rules", "", "YAML file of include/exclude rules, replaces the default bill-generation exclusion")
+	fs.BoolVar(&keepBillGeneration, "keep-bill-generation", false, "do not skip bill-generation entries")
+	fs.BoolVar(&keepUnknown, "keep-unknown-events", false, "keep transactions of unknown event codes as rows without amounts instead of reporting them")
+	fs.BoolVar(&validate, "validate", false, "write a schema drift report instead of the payment report")
+	fs.StringVar(&strict, "strict", "none", "with -validate, fail on drift: none, required or all")
+	fs.IntVar(&workers, "workers", 1, "with -mode report or bills, messages decoded in parallel")
+	fs.StringVar(&maskKeyFile, "mask-key-file", "", "file holding the HMAC key that masks account_number, ref1 and ref2 before any mode runs")
+	fs.StringVar(&vault, "vault", "", "with -mask-key-file, local file the tokens are merged into; with -mode unmask, the vault to read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	strictness, err := testnaka.ParseStrictness(strict)
+	if err != nil {
+		return err
+	}
+
+	opts := testnaka.DefaultOptions()
+	if opts.Output.Format, err = testnaka.ParseFormat(format); err != nil {
+		return err
+	}
+	opts.Output.BOM = bom
+	opts.Workers = workers
+	if keepUnknown {
+		opts.Handlers = testnaka.NewRegistry()
+		opts.Handlers.SetFallback(testnaka.IgnoreEventHandler{})
+	}
+	if keepBillGeneration {
+		opts.Rules = testnaka.Rules{}
+	}
+	if rulesFile != "" {
+		if opts.Rules, err = testnaka.LoadRules(rulesFile); err != nil {
+			return err
+		}
+	}
+	for _, a := range accounts {
+		n, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid -account %q: %w", a, err)
+		}
+		opts.Filter.AccountNumbers = append(opts.Filter.AccountNumbers, n)
+	}
+	opts.Filter.EventCodes = events
+	opts.Filter.Descriptions = entries
+	opts.Filter.Channels = channels
+	opts.Filter.PathPrefixes = prefixes
+	if opts.Filter.DateFrom, err = parseDate("from", from); err != nil {
+		return err
+	}
+	if opts.Filter.DateTo, err = parseDate("to", to); err != nil {
+		return err
+	}
+
+	asOfDate, err := parseDate("as-of", asOf)
+	if err != nil {
+		return err
+	}
+	if asOfDate.Null() {
+		asOfDate = null.NewDate(date.NowDate())
+	}
+	priorAsOfDate, err := parseDate("prior-as-of", priorAsOf)
+	if err != nil {
+		return err
+	}
+	thresholds := testnaka.StagingThresholds{Stage2Days: stage2, Stage3Days: stage3}
+	if err := thresholds.Validate(); err != nil {
+		return err
+	}
+
+	// nothing is opened until the mode and its flags are known good, an
+	// existing -out file survives a mistyped command
+	if !validate {
+		if err := checkMode(mode, modeFlags{
+			group: group, after: after, maskKeyFile: maskKeyFile, vault: vault,
+			priorAsOf: priorAsOfDate, asOf: asOfDate.Val,
+		}); err != nil {
+			return err
+		}
+	}
+
+	r, closeIn, err := openInput(in, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	masker, err := loadMasker(maskKeyFile, vault)
+	if err != nil {
+		return err
+	}
+	opts.Masker = masker
+	w, finishOut, err := openOutput(out, stdout)
+	if err != nil {
+		return err
+	}
+
+	// an interrupt stops report and bills between transactions
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	switch {
+	case validate:
+		err = runValidate(r, w, opts, strictness)
+	case mode == "report":
+		err = runReport(ctx, r, w, opts, stderr)
+	case mode == "bills":
+		err = runBills(ctx, r, w, opts, stderr)
+	case mode == "summary":
+		err = runSummary(r, w, opts, testnaka.SummaryGroup(group), stderr)
+	case mode == "consistency":
+		err = runConsistency(r, w, opts, stderr)
+	case mode == "payoff":
+		err = runPayoff(r, w, opts, stderr)
+	case mode == "timeline":
+		err = runTimeline(r, w, opts, stderr)
+	case mode == "backdate", mode == "awaiting":
+		err = runBackdate(r, w, opts, mode == "awaiting", asOfDate.Val, stderr)
+	case mode == "aging", mode == "aging-buckets":
+		err = runAging(r, w, opts, mode == "aging-buckets", asOfDate.Val, stderr)
+	case mode == "stages":
+		err = runStages(r, w, opts, asOfDate.Val, thresholds, stderr)
+	case mode == "stage-migration":
+		err = runStageMigration(r, w, opts, priorAsOfDate, asOfDate.Val, thresholds, stderr)
+	case mode == "ids":
+		err = runIDs(r, w, opts, stderr)
+	case mode == "entries":
+		err = runEntryPoints(r, w, opts, stderr)
+	case mode == "diff":
+		err = runDiff(r, after, w, opts, stderr)
+	case mode == "mask":
+		err = runMask(r, w, opts, stderr)
+	case mode == "unmask":
+		err = runUnmask(r, w, vault)
+	default:
+		err = fmt.Errorf("unknown -mode %q", mode)
+	}
+	// the report is not kept without the tokens to unmask it
+	if err == nil && masker != nil && vault != "" {
+		err = testnaka.SaveVault(vault, masker.Vault())
+	}
+	return finishOut(err)
+}
+
+// modeFlags are the flags some modes cannot run without
+type modeFlags struct {
+	group, after       string
+	maskKeyFile, vault string
+	priorAsOf          null.Date
+	asOf               date.Date
+}
+
+// checkMode reports an unknown mode or a flag it is missing
+func checkMode(mode string, f modeFlags) error {
+	switch mode {
+	case "report", "bills", "consistency", "payoff", "timeline", "backdate", "awaiting",
+		"aging", "aging-buckets", "stages", "ids", "entries":
+		return nil
+	case "summary":
+		_, err := testnaka.ParseSummaryGroup(f.group)
+		return err
+	case "stage-migration":
+		if f.priorAsOf.Null() {
+			return fmt.Errorf("-mode stage-migration needs -prior-as-of")
+		}
+		if !f.priorAsOf.Val.Before(f.asOf) {
+			return fmt.Errorf("-prior-as-of %s is not before -as-of %s", f.priorAsOf.Val, f.asOf)
+		}
+		return nil
+	case "diff":
+		if f.after == "" {
+			return fmt.Errorf("-mode diff needs -after")
+		}
+		return nil
+	case "mask":
+		if f.maskKeyFile == "" {
+			return fmt.Errorf("-mode mask needs -mask-key-file")
+		}
+		return nil
+	case "unmask":
+		if f.vault == "" {
+			return fmt.Errorf("-mode unmask needs -vault")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown -mode %q", mode)
+}
+
+func runReport(ctx context.Context, r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	errs, err := testnaka.ReportContext(ctx, r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range errs {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runBills(ctx context.Context, r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	errs, err := testnaka.ReportBillsContext(ctx, r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range errs {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runSummary(r io.Reader, w io.Writer, opts testnaka.Options, by testnaka.SummaryGroup, stderr io.Writer) error {
+	res, err := testnaka.ReportSummary(r, w, opts, by)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runConsistency(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	res, err := testnaka.ReportConsistency(r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	fmt.Fprintf(stderr, "checked %d transactions, %d findings\n", res.Checked, len(res.Findings))
+	return nil
+}
+
+func runPayoff(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	res, err := testnaka.ReportPayoff(r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	fmt.Fprintf(stderr, "%d early payoffs, %d unbalanced\n", len(res.Rows), len(res.Unbalanced()))
+	return nil
+}
+
+func runTimeline(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	res, err := testnaka.ReportTimeline(r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runBackdate(r io.Reader, w io.Writer, opts testnaka.Options, awaiting bool, asOf date.Date, stderr io.Writer) error {
+	adjustments := w
+	if awaiting {
+		adjustments = io.Discard
+	}
+	res, err := testnaka.ReportBackdates(r, adjustments, opts, asOf)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	if awaiting {
+		return testnaka.WriteTable(w, opts.Output, testnaka.AwaitingBackdateColumns, res.Awaiting)
+	}
+	fmt.Fprintf(stderr, "%d of %d adjustments unpaired\n", res.Unpaired(), len(res.Adjustments))
+	return nil
+}
+
+func runAging(r io.Reader, w io.Writer, opts testnaka.Options, buckets bool, asOf date.Date, stderr io.Writer) error {
+	accounts := w
+	if buckets {
+		accounts = io.Discard
+	}
+	res, err := testnaka.ReportAging(r, accounts, opts, asOf)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	if buckets {
+		return testnaka.WriteTable(w, opts.Output, testnaka.AgingBucketColumns, res.Buckets)
+	}
+	return nil
+}
+
+func runStages(r io.Reader, w io.Writer, opts testnaka.Options, asOf date.Date, th testnaka.StagingThresholds, stderr io.Writer) error {
+	res, err := testnaka.ReportStages(r, w, opts, asOf, th)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runStageMigration(r io.Reader, w io.Writer, opts testnaka.Options, from null.Date, to date.Date, th testnaka.StagingThresholds, stderr io.Writer) error {
+	res, err := testnaka.ReportStageMigration(r, w, opts, from.Val, to, th)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runIDs(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	report, err := testnaka.ValidateIDs(r, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range report.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	fmt.Fprintf(stderr, "checked %d transactions, %d issues\n", report.Checked, len(report.Issues))
+	return testnaka.WriteTable(w, opts.Output, testnaka.IDIssueColumns, report.Issues)
+}
+
+func runEntryPoints(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	res, err := testnaka.ReportEntryPoints(r, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.Errors {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runDiff(before io.Reader, afterPath string, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	file, err := os.Open(afterPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	res, err := testnaka.ReportDiff(before, file, w, opts)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range res.BeforeErrors {
+		fmt.Fprintln(stderr, "before:", txErr)
+	}
+	for _, txErr := range res.AfterErrors {
+		fmt.Fprintln(stderr, "after:", txErr)
+	}
+	return nil
+}
+
+func runMask(r io.Reader, w io.Writer, opts testnaka.Options, stderr io.Writer) error {
+	// the masked response keeps every transaction of the input
+	all := testnaka.Options{Workers: opts.Workers, Masker: opts.Masker}
+	errs, err := testnaka.ReportMasked(r, w, all)
+	if err != nil {
+		return err
+	}
+	for _, txErr := range errs {
+		fmt.Fprintln(stderr, txErr)
+	}
+	return nil
+}
+
+func runUnmask(r io.Reader, w io.Writer, vault string) error {
+	v, err := testnaka.LoadVault(vault)
+	if err != nil {
+		return err
+	}
+	body, err := testnaka.DecodeBody(r)
+	if err != nil {
+		return err
+	}
+	if body, err = v.UnmaskBody(body); err != nil {
+		return err
+	}
+	return testnaka.EncodeBody(w, body)
+}
+
+// loadMasker returns the masker of the key in file, nil without a file.
+// The tokens of the vault file, when there is one, are preloaded so a
+// collision with them fails its transaction instead of the final save.
+func loadMasker(file, vault string) (*testnaka.Masker, error) {
+	if file == "" {
+		return nil, nil
+	}
+	key, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	masker, err := testnaka.NewMasker(bytes.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+	if vault != "" {
+		saved, err := testnaka.LoadVault(vault)
+		if err != nil {
+			return nil, err
+		}
+		if err := masker.Preload(saved); err != nil {
+			return nil, err
+		}
+	}
+	return masker, nil
+}
+
+func runValidate(r io.Reader, w io.Writer, opts testnaka.Options, strictness testnaka.Strictness) error {
+	v := testnaka.NewSchemaValidator(testnaka.DefaultSchemas, 3)
+	if _, err := testnaka.Each(r, opts, func(tx testnaka.Transaction) error {
+		v.Check(tx)
+		return nil
+	}); err != nil {
+		return err
+	}
+	report := v.Report()
+	if err := testnaka.WriteSchemaReport(w, report); err != nil {
+		return err
+	}
+	return report.Err(strictness)
+}
+
+func parseDate(name, v string) (null.Date, error) {
+	if v == "" {
+		return null.Date{}, nil
+	}
+	d, err := null.NewDates(v)
+	if err != nil {
+		return d, fmt.Errorf("invalid -%s %q: %w", name, v, err)
+	}
+	return d, nil
+}
+
+func openInput(path string, stdin io.Reader) (io.Reader, func() error, error) {
+	if path == "-" {
+		return stdin, func() error { return nil }, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func openOutput(path string, stdout io.Writer) (io.Writer, func(error) error, error) {
+	if path == "-" {
+		return stdout, func(err error) error { return err }, nil
+	}
+	// the report goes to a temporary file of the same directory that
+	// replaces path once complete, a failed run leaves path as it was
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, nil, err
+	}
+	finish := func(err error) error {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), mode)
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), path)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+		return err
+	}
+	return f, finish, nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/note/testnaka"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixture = "../../testnaka/query-dloan-payment-publishMessageDetail_response.json"
+
+func TestRun(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"-in", fixture, "-account", "190000003836", "-event", "due_bills,others", "-from", "2025-01-15"}, nil, &out, &errOut)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines[1:] {
+		assert.True(t, strings.HasPrefix(line, "190000003836|"), line)
+	}
+}
+
+func TestRun_stdin(t *testing.T) {
+	var out bytes.Buffer
+	in := `{"rs_body":[{"account_number":1,"event_code":"fee","message":"{\"fee_amount\":100.00}"}]}`
+	err := run(nil, strings.NewReader(in), &out, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "1|fee|||||100.00||||")
+}
+
+func TestRun_badDate(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-from", "15/01/2025"}, strings.NewReader(""), &out, &out)
+	assert.Error(t, err)
+}
+
+func TestRun_badModeKeepsOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("last month\n"), 0o644))
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		{"-in", fixture, "-out", file, "-mode", "reprot"},
+		{"-in", fixture, "-out", file, "-mode", "summary", "-group", "branch"},
+		{"-in", fixture, "-out", file, "-mode", "stage-migration", "-as-of", "2025-02-28"},
+		{"-in", fixture, "-out", file, "-mode", "diff"},
+	} {
+		assert.Error(t, run(args, nil, &out, &out), args)
+	}
+	raw, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "last month\n", string(raw))
+}
+
+func TestRun_failedModeKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("last month\n"), 0o640))
+	var out bytes.Buffer
+	// the after file is only opened once the output is
+	err := run([]string{"-in", fixture, "-out", file, "-mode", "diff", "-after", filepath.Join(dir, "missing.json")}, nil, &out, &out)
+	assert.Error(t, err)
+	raw, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "last month\n", string(raw))
+
+	assert.NoError(t, run([]string{"-in", fixture, "-out", file, "-mode", "summary", "-group", "account"}, nil, &out, &out))
+	raw, err = os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), "AccountNumber|"))
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestRun_validate(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-validate", "-strict", "all"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "0 drifts")
+
+	in := `{"rs_body":[{"chrono_sequence":"C1","event_code":"fee","message":"{\"fee_amount\":1.00}"}]}`
+	out.Reset()
+	err = run([]string{"-validate", "-strict", "required"}, strings.NewReader(in), &out, &out)
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), "missing_key")
+}
+
+func TestRun_bills(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "bills", "-event", "fee"}, nil, &out, &out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines[1:] {
+		assert.Contains(t, line, "|fee|fee|")
+	}
+
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "nope"}, nil, &out, &out))
+}
+
+func TestRun_format(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-format", "csv", "-bom", "-event", "fee"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "\ufeffAccountNumber,EventCode,"))
+
+	assert.Error(t, run([]string{"-format", "xml"}, strings.NewReader(""), &out, &out))
+}
+
+func TestRun_rules(t *testing.T) {
+	rules := t.TempDir() + "/rules.yaml"
+	assert.NoError(t, os.WriteFile(rules, []byte("include:\n  - field: event_code\n    equals: others\n"), 0o644))
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-rules", rules}, nil, &out, &out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 68)
+}
+
+func TestRun_summary(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "summary", "-group", "date"}, nil, &out, &out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[1], "|2025-01-15|252|"), lines[1])
+	}
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "summary", "-group", "month"}, nil, &out, &out))
+}
+
+func TestRun_consistency(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "consistency"}, nil, &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, "ChronoSequence|AccountNumber|AccountSequence|EventCode|Field|MessageAmount|NestedAmount|Delta\n", out.String())
+	assert.Contains(t, errOut.String(), "checked 252 transactions, 0 findings")
+}
+
+func TestRun_payoff(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "payoff", "-account", "190000026836"}, nil, &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+	assert.Contains(t, errOut.String(), "1 early payoffs, 0 unbalanced")
+}
+
+func TestRun_timeline(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "timeline", "-account", "190000003836"}, nil, &out, &out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[2], "|-107|")
+		assert.Contains(t, lines[2], "|7501.65|289.01|0.00|545.34|0.00|")
+	}
+}
+
+func TestRun_backdate(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "backdate", "-account", "190000076671"}, nil, &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), "190000076671|-108|")
+	assert.Equal(t, "1 of 2 adjustments unpaired\n", errOut.String())
+
+	out.Reset()
+	err = run([]string{"-in", fixture, "-mode", "awaiting", "-account", "190000076671", "-as-of", "2025-01-24"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "AccountNumber|Transactions|OldestOriginalDate|AgeDays\n190000076671|4|2025-01-14|10\n", out.String())
+}
+
+func TestRun_aging(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "aging", "-account", "290000163536", "-as-of", "2025-02-28"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "AccountNumber|OldestDueDate|DaysPastDue|Bucket|UnpaidPrincipalAmount|UnpaidInterestAmount|UnpaidPenaltyAmount|UnpaidVatAmount|UnpaidAmount\n"+
+		"290000163536|2024-11-20|100|90+|3699.76|1429.93|0.00|0.00|5129.69\n", out.String())
+
+	out.Reset()
+	err = run([]string{"-in", fixture, "-mode", "aging-buckets", "-as-of", "2025-01-31", "-format", "csv"}, nil, &out, &out)
+	assert.NoError(t, err)
+	// csv rows end with CRLF
+	lines := strings.Split(strings.TrimSpace(out.String()), "\r\n")
+	if assert.Len(t, lines, 6) {
+		assert.Equal(t, "1-30,2,530.32,125.70,0.00,45.92,701.94", lines[2])
+		assert.Equal(t, "61-90,1,3699.76,1429.93,0.00,0.00,5129.69", lines[4])
+	}
+}
+
+func TestRun_stages(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "stages", "-account", "290000158400", "-as-of", "2025-02-28", "-stage2-days", "60"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "AccountNumber|OldestDueDate|DaysPastDue|Stage|UnpaidAmount\n290000158400|2024-12-15|75|2|164.81\n", out.String())
+
+	out.Reset()
+	err = run([]string{"-in", fixture, "-mode", "stage-migration", "-prior-as-of", "2025-01-31", "-as-of", "2025-02-28"}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "FromStage|ToStage1|ToStage2|ToStage3|Accounts|UnpaidAmount\n"+
+		"1|85|2|0|87|701.94\n"+
+		"2|0|1|1|2|5294.50\n"+
+		"3|0|0|0|0|0.00\n"+
+		"new|0|0|0|0|0.00\n", out.String())
+
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "stage-migration", "-as-of", "2025-02-28"}, nil, &out, &out))
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "stage-migration", "-prior-as-of", "2025-03-01", "-as-of", "2025-02-28"}, nil, &out, &out))
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "stages", "-stage2-days", "90", "-stage3-days", "30"}, nil, &out, &out))
+}
+
+func TestRun_ids(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "ids"}, nil, &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Index|ChronoSequence|Field|Kind|Detail\n", out.String())
+	assert.Contains(t, errOut.String(), "checked 252 transactions, 0 issues")
+}
+
+func TestRun_entries(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "entries", "-channel", "rest", "-path-prefix", "/dloan-payment/v1/adjustment/"}, nil, &out, &out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[1], "REST|POST|/dloan-payment/v1/adjustment/repayment/back-date|209|"))
+	}
+}
+
+func TestRun_diff(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-in", fixture, "-mode", "diff", "-after", fixture}, nil, &out, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "diff"}, nil, &out, &out))
+}
+
+func TestRun_workers(t *testing.T) {
+	var want, got bytes.Buffer
+	assert.NoError(t, run([]string{"-in", fixture}, nil, &want, &want))
+	assert.NoError(t, run([]string{"-in", fixture, "-workers", "8"}, nil, &got, &got))
+	assert.Equal(t, want.String(), got.String())
+}
+
+func TestRun_keepUnknownEvents(t *testing.T) {
+	in := `{"rs_body":[{"account_number":1,"event_code":"write_off","message":"{}"}]}`
+	var out, errOut bytes.Buffer
+	assert.NoError(t, run(nil, strings.NewReader(in), &out, &errOut))
+	assert.Contains(t, errOut.String(), "unknown event code")
+
+	out.Reset()
+	errOut.Reset()
+	assert.NoError(t, run([]string{"-keep-unknown-events"}, strings.NewReader(in), &out, &errOut))
+	assert.Empty(t, errOut.String())
+	assert.Contains(t, out.String(), "1|write_off|")
+}
+
+func TestRun_mask(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	vault := filepath.Join(dir, "vault.json")
+	masked := filepath.Join(dir, "masked.json")
+	assert.NoError(t, os.WriteFile(keyFile, []byte("secret\n"), 0o600))
+
+	var out bytes.Buffer
+	assert.NoError(t, run([]string{"-in", fixture, "-mode", "mask", "-mask-key-file", keyFile, "-vault", vault, "-out", masked}, nil, &out, &out))
+	raw, err := os.ReadFile(masked)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), "190000003836")
+	_, err = os.Stat(vault)
+	assert.NoError(t, err)
+
+	// reports of the masked input carry tokens only
+	out.Reset()
+	assert.NoError(t, run([]string{"-in", fixture, "-mask-key-file", keyFile, "-mode", "summary", "-group", "account"}, nil, &out, &out))
+	assert.NotContains(t, out.String(), "190000003836")
+	assert.Contains(t, out.String(), "|99")
+
+	out.Reset()
+	assert.NoError(t, run([]string{"-in", masked, "-mode", "unmask", "-vault", vault}, nil, &out, &out))
+	original, err := os.ReadFile(fixture)
+	assert.NoError(t, err)
+	var want bytes.Buffer
+	assert.NoError(t, json.Compact(&want, original))
+	assert.Equal(t, want.String()+"\n", out.String())
+
+	assert.Error(t, run([]string{"-in", fixture, "-mode", "mask"}, nil, &out, &out))
+	assert.Error(t, run([]string{"-in", masked, "-mode", "unmask"}, nil, &out, &out))
+
+	// a message that cannot be masked fails its transaction only
+	in := `{"rs_body":[{"account_number":1,"event_code":"others","message":"{"},{"account_number":2,"event_code":"others","message":"{}"}]}`
+	var errOut bytes.Buffer
+	out.Reset()
+	assert.NoError(t, run([]string{"-mode", "mask", "-mask-key-file", keyFile}, strings.NewReader(in), &out, &errOut))
+	assert.Contains(t, errOut.String(), "rs_body[0]")
+	assert.Equal(t, 1, strings.Count(out.String(), "account_number"))
+
+	// a value colliding with a token of the saved vault fails its
+	// transaction, its token is never written
+	m, _ := testnaka.NewMasker([]byte("secret"))
+	token, _ := m.Token("2")
+	conflict := filepath.Join(dir, "conflict.json")
+	assert.NoError(t, testnaka.SaveVault(conflict, testnaka.Vault{token: "3"}))
+	errOut.Reset()
+	out.Reset()
+	assert.NoError(t, run([]string{"-mode", "mask", "-mask-key-file", keyFile, "-vault", conflict}, strings.NewReader(in), &out, &errOut))
+	assert.Contains(t, errOut.String(), "rs_body[1]")
+	assert.Contains(t, errOut.String(), "collide")
+	assert.NotContains(t, out.String(), token)
+	saved, err := testnaka.LoadVault(conflict)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", saved[token])
+}
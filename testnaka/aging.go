@@ -0,0 +1,298 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// NoDueDate is the oldest_bill_due_date and oldest_stmt_due_date of
+// accounts without an unpaid bill or statement
+const NoDueDate = "9999-12-31"
+
+// AgingBucket is a range of days past due
+type AgingBucket string
+
+const (
+	BucketCurrent AgingBucket = "current"
+	Bucket1To30   AgingBucket = "1-30"
+	Bucket31To60  AgingBucket = "31-60"
+	Bucket61To90  AgingBucket = "61-90"
+	BucketOver90  AgingBucket = "90+"
+)
+
+// AgingBuckets lists the buckets from current to the oldest
+var AgingBuckets = []AgingBucket{BucketCurrent, Bucket1To30, Bucket31To60, Bucket61To90, BucketOver90}
+
+// BucketOf returns the bucket of days past due
+func BucketOf(days int64) AgingBucket {
+	switch {
+	case days <= 0:
+		return BucketCurrent
+	case days <= 30:
+		return Bucket1To30
+	case days <= 60:
+		return Bucket31To60
+	case days <= 90:
+		return Bucket61To90
+	}
+	return BucketOver90
+}
+
+// AccountAging is the delinquency of one account as of a date. The unpaid
+// amounts are those of the latest state of every bill of the account, none
+// when a later transaction reports nothing due.
+type AccountAging struct {
+	AccountNumber null.Int64
+	// Earliest due date still unpaid, null when nothing is
+	OldestDueDate null.Date
+	// Days from OldestDueDate to the as-of date, zero when not yet due
+	DaysPastDue           int64
+	Bucket                AgingBucket
+	UnpaidPrincipalAmount decimal.Dec2
+	UnpaidInterestAmount  decimal.Dec2
+	UnpaidPenaltyAmount   decimal.Dec2
+	UnpaidVatAmount       decimal.Dec2
+}
+
+// UnpaidAmount is the sum of the unpaid amounts of the account
+func (a AccountAging) UnpaidAmount() decimal.Dec2 {
+	return a.UnpaidPrincipalAmount.Add(a.UnpaidInterestAmount).Add(a.UnpaidPenaltyAmount).Add(a.UnpaidVatAmount)
+}
+
+// AgingBucketTotal holds the accounts and unpaid amounts of one bucket
+type AgingBucketTotal struct {
+	Bucket                AgingBucket
+	Accounts              int
+	UnpaidPrincipalAmount decimal.Dec2
+	UnpaidInterestAmount  decimal.Dec2
+	UnpaidPenaltyAmount   decimal.Dec2
+	UnpaidVatAmount       decimal.Dec2
+}
+
+// UnpaidAmount is the sum of the unpaid amounts of the bucket
+func (b AgingBucketTotal) UnpaidAmount() decimal.Dec2 {
+	return b.UnpaidPrincipalAmount.Add(b.UnpaidInterestAmount).Add(b.UnpaidPenaltyAmount).Add(b.UnpaidVatAmount)
+}
+
+// AgingResult holds the accounts ordered most days past due first and the
+// totals of every bucket in AgingBuckets order
+type AgingResult struct {
+	Accounts []AccountAging
+	Buckets  []AgingBucketTotal
+	Errors   []TransactionError
+}
+
+// AgeAccounts computes the days past due of every account of body as of
+// asOf, usually date.NowDate(), and totals them by bucket. Transactions
+// dated after asOf are left out.
+func AgeAccounts(body Body, opts Options, asOf date.Date) AgingResult {
+	t := newAgingTracker(asOf)
+	errs := eachTransaction(body, opts, t.add)
+	return t.result(errs)
+}
+
+// ReportAging ages the accounts of the response read from r and writes
+// them to w. The bucket totals are returned for the caller to write with
+// AgingBucketColumns.
+func ReportAging(r io.Reader, w io.Writer, opts Options, asOf date.Date) (AgingResult, error) {
+	t := newAgingTracker(asOf)
+	errs, err := Each(r, opts, t.add)
+	res := t.result(errs)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, AccountAgingColumns, res.Accounts)
+}
+
+// agingBill is the latest state of a bill and the transaction it came from
+type agingBill struct {
+	chrono string
+	bill   Bill
+}
+
+// agingAccount is what an account's transactions say about its arrears.
+// Later chrono sequences supersede earlier ones, whatever the rs_body order.
+type agingAccount struct {
+	number null.Int64
+	bills  map[int64]agingBill
+	// chrono sequence the oldest due dates were read from
+	oldestChrono string
+	oldestBill   null.Date
+	oldestStmt   null.Date
+}
+
+type agingTracker struct {
+	asOf     date.Date
+	accounts map[int64]*agingAccount
+}
+
+func newAgingTracker(asOf date.Date) *agingTracker {
+	return &agingTracker{asOf: asOf, accounts: map[int64]*agingAccount{}}
+}
+
+// add records tx unless it is dated after the as-of date. Undated
+// transactions count.
+func (t *agingTracker) add(tx Transaction) error {
+	if tx.TransactionDate.NotNull() && tx.TransactionDate.Val.After(t.asOf) {
+		return nil
+	}
+	var msg struct {
+		OtherProperties map[string]interface{} `json:"other_properties"`
+	}
+	if err := json.Unmarshal([]byte(tx.Message.String()), &msg); err != nil {
+		return fmt.Errorf("unmarshal %s message: %w", tx.EventCode, err)
+	}
+	var bills []Bill
+	if err := decodeNested(msg.OtherProperties, "bills", &bills); err != nil {
+		return err
+	}
+	oldestBill, err := oldestDueDate(msg.OtherProperties, "oldest_bill_due_date")
+	if err != nil {
+		return err
+	}
+	oldestStmt, err := oldestDueDate(msg.OtherProperties, "oldest_stmt_due_date")
+	if err != nil {
+		return err
+	}
+
+	a, ok := t.accounts[tx.AccountNumber.Val]
+	if !ok {
+		a = &agingAccount{number: tx.AccountNumber, bills: map[int64]agingBill{}}
+		t.accounts[tx.AccountNumber.Val] = a
+	}
+	chrono := tx.ChronoSequence.String()
+	for _, b := range bills {
+		if prev, ok := a.bills[b.BillSequence.Val]; ok && compareStrings(prev.chrono, chrono) > 0 {
+			continue
+		}
+		a.bills[b.BillSequence.Val] = agingBill{chrono: chrono, bill: b}
+	}
+	// messages without the keys, or with them empty, say nothing
+	if (oldestBill.reported || oldestStmt.reported) && compareStrings(a.oldestChrono, chrono) <= 0 {
+		a.oldestChrono = chrono
+		a.oldestBill = oldestBill.date
+		a.oldestStmt = oldestStmt.date
+	}
+	return nil
+}
+
+func (t *agingTracker) result(errs []TransactionError) AgingResult {
+	res := AgingResult{Errors: errs}
+	totals := make(map[AgingBucket]*AgingBucketTotal, len(AgingBuckets))
+	for _, b := range AgingBuckets {
+		res.Buckets = append(res.Buckets, AgingBucketTotal{Bucket: b})
+	}
+	for i := range res.Buckets {
+		totals[res.Buckets[i].Bucket] = &res.Buckets[i]
+	}
+
+	for _, a := range t.accounts {
+		aging := AccountAging{AccountNumber: a.number}
+		oldest := earliestDate(a.oldestBill, a.oldestStmt)
+		for _, b := range a.bills {
+			// the oldest due dates reported since supersede the bill, with
+			// nothing due they clear it
+			stale := compareStrings(b.chrono, a.oldestChrono) < 0
+			if stale && oldest.Null() {
+				continue
+			}
+			bill := b.bill
+			unpaid := bill.UnpaidPrincipalAmount.Val.Add(bill.UnpaidInterestAmount.Val).
+				Add(bill.UnpaidPenaltyAmount.Val).Add(bill.UnpaidVatAmount.Val)
+			if !unpaid.GTZero() {
+				continue
+			}
+			aging.UnpaidPrincipalAmount = aging.UnpaidPrincipalAmount.Add(bill.UnpaidPrincipalAmount.Val)
+			aging.UnpaidInterestAmount = aging.UnpaidInterestAmount.Add(bill.UnpaidInterestAmount.Val)
+			aging.UnpaidPenaltyAmount = aging.UnpaidPenaltyAmount.Add(bill.UnpaidPenaltyAmount.Val)
+			aging.UnpaidVatAmount = aging.UnpaidVatAmount.Add(bill.UnpaidVatAmount.Val)
+			if due, err := null.NewDates(bill.BillDueDate.String()); err == nil && !stale {
+				oldest = earliestDate(oldest, due)
+			}
+		}
+		aging.OldestDueDate = oldest
+		if oldest.NotNull() {
+			if days := daysBetween(oldest.Val, t.asOf); days > 0 {
+				aging.DaysPastDue = days
+			}
+		}
+		aging.Bucket = BucketOf(aging.DaysPastDue)
+		res.Accounts = append(res.Accounts, aging)
+
+		total := totals[aging.Bucket]
+		total.Accounts++
+		total.UnpaidPrincipalAmount = total.UnpaidPrincipalAmount.Add(aging.UnpaidPrincipalAmount)
+		total.UnpaidInterestAmount = total.UnpaidInterestAmount.Add(aging.UnpaidInterestAmount)
+		total.UnpaidPenaltyAmount = total.UnpaidPenaltyAmount.Add(aging.UnpaidPenaltyAmount)
+		total.UnpaidVatAmount = total.UnpaidVatAmount.Add(aging.UnpaidVatAmount)
+	}
+	sort.Slice(res.Accounts, func(i, j int) bool {
+		a, b := res.Accounts[i], res.Accounts[j]
+		if a.DaysPastDue != b.DaysPastDue {
+			return a.DaysPastDue > b.DaysPastDue
+		}
+		return a.AccountNumber.Val < b.AccountNumber.Val
+	})
+	return res
+}
+
+// reportedDate is an oldest due date key of other_properties. A reported
+// NoDueDate is null.
+type reportedDate struct {
+	reported bool
+	date     null.Date
+}
+
+func oldestDueDate(props map[string]interface{}, key string) (reportedDate, error) {
+	s, err := infoString(props, key)
+	if err != nil || s == "" {
+		return reportedDate{}, err
+	}
+	if s == NoDueDate {
+		return reportedDate{reported: true}, nil
+	}
+	d, err := null.NewDates(s)
+	if err != nil {
+		return reportedDate{}, fmt.Errorf("other_properties[%s]: %w", key, err)
+	}
+	return reportedDate{reported: true, date: d}, nil
+}
+
+// earliestDate returns the earlier of a and b, ignoring null ones
+func earliestDate(a, b null.Date) null.Date {
+	if a.Null() || (b.NotNull() && b.Val.Before(a.Val)) {
+		return b
+	}
+	return a
+}
+
+// AccountAgingColumns are the columns of the per-account aging report
+var AccountAgingColumns = []Column[AccountAging]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(a AccountAging) string { return int64Cell(a.AccountNumber) }},
+	{Name: "OldestDueDate", Width: 10, Value: func(a AccountAging) string { return dateCell(a.OldestDueDate) }},
+	{Name: "DaysPastDue", Width: 6, Numeric: true, Value: func(a AccountAging) string { return strconv.FormatInt(a.DaysPastDue, 10) }},
+	{Name: "Bucket", Width: 7, Value: func(a AccountAging) string { return string(a.Bucket) }},
+	{Name: "UnpaidPrincipalAmount", Width: 21, Numeric: true, Value: func(a AccountAging) string { return a.UnpaidPrincipalAmount.String() }},
+	{Name: "UnpaidInterestAmount", Width: 20, Numeric: true, Value: func(a AccountAging) string { return a.UnpaidInterestAmount.String() }},
+	{Name: "UnpaidPenaltyAmount", Width: 19, Numeric: true, Value: func(a AccountAging) string { return a.UnpaidPenaltyAmount.String() }},
+	{Name: "UnpaidVatAmount", Width: 15, Numeric: true, Value: func(a AccountAging) string { return a.UnpaidVatAmount.String() }},
+	{Name: "UnpaidAmount", Width: 15, Numeric: true, Value: func(a AccountAging) string { return a.UnpaidAmount().String() }},
+}
+
+// AgingBucketColumns are the columns of the aging bucket totals
+var AgingBucketColumns = []Column[AgingBucketTotal]{
+	{Name: "Bucket", Width: 7, Value: func(b AgingBucketTotal) string { return string(b.Bucket) }},
+	{Name: "Accounts", Width: 6, Numeric: true, Value: func(b AgingBucketTotal) string { return strconv.Itoa(b.Accounts) }},
+	{Name: "UnpaidPrincipalAmount", Width: 21, Numeric: true, Value: func(b AgingBucketTotal) string { return b.UnpaidPrincipalAmount.String() }},
+	{Name: "UnpaidInterestAmount", Width: 20, Numeric: true, Value: func(b AgingBucketTotal) string { return b.UnpaidInterestAmount.String() }},
+	{Name: "UnpaidPenaltyAmount", Width: 19, Numeric: true, Value: func(b AgingBucketTotal) string { return b.UnpaidPenaltyAmount.String() }},
+	{Name: "UnpaidVatAmount", Width: 15, Numeric: true, Value: func(b AgingBucketTotal) string { return b.UnpaidVatAmount.String() }},
+	{Name: "UnpaidAmount", Width: 15, Numeric: true, Value: func(b AgingBucketTotal) string { return b.UnpaidAmount().String() }},
+}
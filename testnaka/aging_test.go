@@ -0,0 +1,139 @@
+package testnaka
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketOf(t *testing.T) {
+	for days, want := range map[int64]AgingBucket{
+		-3: BucketCurrent, 0: BucketCurrent, 1: Bucket1To30, 30: Bucket1To30, 31: Bucket31To60,
+		60: Bucket31To60, 61: Bucket61To90, 90: Bucket61To90, 91: BucketOver90, 400: BucketOver90,
+	} {
+		assert.Equal(t, want, BucketOf(days), days)
+	}
+}
+
+func TestAgeAccounts(t *testing.T) {
+	bill := func(seq int, due string, unpaid string) string {
+		return `{\"bill_sequence\":` + strconv.Itoa(seq) + `,\"bill_due_date\":\"` + due + `\",\"unpaid_principal_amount\":` + unpaid + `,\"unpaid_interest_amount\":0.00}`
+	}
+	tx := func(account int64, chrono, props string) Transaction {
+		return Transaction{
+			AccountNumber:  null.NewInt64(account),
+			ChronoSequence: null.NewString(chrono),
+			EventCode:      null.NewString("due_bills"),
+			Message:        null.NewString(`{"other_properties":{` + props + `}}`),
+		}
+	}
+	body := Body{ReqBody: []Transaction{
+		// the later chrono sequence wins whatever the rs_body order
+		tx(1, "250115000002", `"bills":"[`+bill(2, "2024-12-10", "0.00")+`]","oldest_bill_due_date":"9999-12-31","oldest_stmt_due_date":"9999-12-31"`),
+		tx(1, "250115000001", `"bills":"[`+bill(1, "2024-11-10", "0.00")+`,`+bill(2, "2024-12-10", "50.00")+`]","oldest_bill_due_date":"2024-12-10"`),
+		// statements count too, empty keys say nothing
+		tx(2, "250115000003", `"oldest_bill_due_date":"9999-12-31","oldest_stmt_due_date":"2024-10-01"`),
+		tx(2, "250115000004", `"oldest_bill_due_date":"","oldest_stmt_due_date":""`),
+		tx(3, "250115000005", `"bills":"[`+bill(9, "2025-01-20", "10.00")+`]"`),
+	}}
+
+	asOf, _ := date.NewDates("2025-01-15")
+	res := AgeAccounts(body, Options{}, asOf)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Accounts, 3) {
+		a := res.Accounts[0]
+		assert.Equal(t, int64(2), a.AccountNumber.Val)
+		assert.Equal(t, "2024-10-01", a.OldestDueDate.String())
+		assert.Equal(t, int64(106), a.DaysPastDue)
+		assert.Equal(t, BucketOver90, a.Bucket)
+		assert.True(t, a.UnpaidAmount().IsZero())
+
+		// paid off by its latest transaction
+		a = res.Accounts[1]
+		assert.Equal(t, int64(1), a.AccountNumber.Val)
+		assert.True(t, a.OldestDueDate.Null())
+		assert.Equal(t, BucketCurrent, a.Bucket)
+		assert.True(t, a.UnpaidAmount().IsZero())
+
+		// unpaid but not yet due
+		a = res.Accounts[2]
+		assert.Equal(t, "2025-01-20", a.OldestDueDate.String())
+		assert.Equal(t, int64(0), a.DaysPastDue)
+		assert.Equal(t, "10.00", a.UnpaidAmount().String())
+	}
+	if assert.Len(t, res.Buckets, len(AgingBuckets)) {
+		assert.Equal(t, 2, res.Buckets[0].Accounts)
+		assert.Equal(t, "10.00", res.Buckets[0].UnpaidAmount().String())
+		assert.Equal(t, 1, res.Buckets[4].Accounts)
+	}
+
+	_, err := oldestDueDate(map[string]interface{}{"oldest_bill_due_date": "31/12/9999"}, "oldest_bill_due_date")
+	assert.Error(t, err)
+}
+
+func TestAgeAccounts_latestState(t *testing.T) {
+	tx := func(account int64, chrono, txDate, props string) Transaction {
+		d, _ := null.NewDates(txDate)
+		return Transaction{
+			TransactionDate: d,
+			AccountNumber:   null.NewInt64(account),
+			ChronoSequence:  null.NewString(chrono),
+			EventCode:       null.NewString("due_bills"),
+			Message:         null.NewString(`{"other_properties":{` + props + `}}`),
+		}
+	}
+	unpaid := `"bills":"[{\"bill_sequence\":1,\"bill_due_date\":\"2024-12-10\",\"unpaid_principal_amount\":50.00}]"`
+	body := Body{ReqBody: []Transaction{
+		// paid after the as-of date
+		tx(1, "250110000001", "2025-01-10", unpaid),
+		tx(1, "250120000001", "2025-01-20", `"oldest_bill_due_date":"9999-12-31","oldest_stmt_due_date":"9999-12-31"`),
+		// paid before it, the later report clears the bill
+		tx(2, "250110000002", "2025-01-10", unpaid),
+		tx(2, "250111000002", "2025-01-11", `"oldest_bill_due_date":"9999-12-31","oldest_stmt_due_date":"9999-12-31"`),
+		// the later report moves the oldest due date past the bill's
+		tx(3, "250110000003", "2025-01-10", unpaid),
+		tx(3, "250111000003", "2025-01-11", `"oldest_bill_due_date":"2025-01-05"`),
+	}}
+
+	asOf, _ := date.NewDates("2025-01-15")
+	res := AgeAccounts(body, Options{}, asOf)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Accounts, 3) {
+		a := res.Accounts[0]
+		assert.Equal(t, int64(1), a.AccountNumber.Val)
+		assert.Equal(t, "2024-12-10", a.OldestDueDate.String())
+		assert.Equal(t, "50.00", a.UnpaidAmount().String())
+
+		a = res.Accounts[1]
+		assert.Equal(t, int64(3), a.AccountNumber.Val)
+		assert.Equal(t, "2025-01-05", a.OldestDueDate.String())
+		assert.Equal(t, int64(10), a.DaysPastDue)
+		assert.Equal(t, "50.00", a.UnpaidAmount().String())
+
+		a = res.Accounts[2]
+		assert.Equal(t, int64(2), a.AccountNumber.Val)
+		assert.True(t, a.OldestDueDate.Null())
+		assert.True(t, a.UnpaidAmount().IsZero())
+	}
+}
+
+func TestReportAging(t *testing.T) {
+	asOf, _ := date.NewDates("2025-01-31")
+	var out bytes.Buffer
+	res, err := ReportAging(openFixture(t), &out, DefaultOptions(), asOf)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	assert.True(t, strings.HasPrefix(out.String(), "AccountNumber|OldestDueDate|DaysPastDue|Bucket|"))
+	assert.Contains(t, out.String(), "\n290000163536|2024-11-20|72|61-90|3699.76|1429.93|0.00|0.00|5129.69\n")
+	assert.Contains(t, out.String(), "\n290000158400|2024-12-15|47|31-60|164.81|0.00|0.00|0.00|164.81\n")
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteTable(&buf, WriteOptions{Format: FormatPipe}, AgingBucketColumns, res.Buckets))
+	assert.Contains(t, buf.String(), "\n1-30|2|530.32|125.70|0.00|45.92|701.94\n")
+	assert.Contains(t, buf.String(), "\n90+|0|0.00|0.00|0.00|0.00|0.00\n")
+}
@@ -0,0 +1,287 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// BackdateFlags are the back-date keys of other_properties, strings in the message
+type BackdateFlags struct {
+	IsAwaitingBackdate      null.Bool
+	OriginalTransactionDate null.Date
+	AdjustmentFlag          null.Bool
+	OriginalAdjustmentFlag  null.Bool
+}
+
+// ParseBackdateFlags parses the back-date keys of props, empty strings are null
+func ParseBackdateFlags(props map[string]interface{}) (BackdateFlags, error) {
+	var f BackdateFlags
+	bools := []struct {
+		key string
+		dst *null.Bool
+	}{
+		{"is_awaiting_backdate", &f.IsAwaitingBackdate},
+		{"adjustment_flag", &f.AdjustmentFlag},
+		{"original_adjustment_flag", &f.OriginalAdjustmentFlag},
+	}
+	for _, b := range bools {
+		s, err := infoString(props, b.key)
+		if err != nil {
+			return f, err
+		}
+		if s == "" {
+			continue
+		}
+		if *b.dst, err = null.NewBools(s); err != nil {
+			return f, fmt.Errorf("other_properties[%s]: %w", b.key, err)
+		}
+	}
+	s, err := infoString(props, "original_transaction_date")
+	if err != nil {
+		return f, err
+	}
+	if s != "" {
+		if f.OriginalTransactionDate, err = null.NewDates(s); err != nil {
+			return f, fmt.Errorf("other_properties[original_transaction_date]: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// Adjustment is a back-dated entry, one with a negative account_sequence,
+// paired with the original posting of the same account, job and event
+// code. Each posting is the original of one adjustment at most.
+type Adjustment struct {
+	AccountNumber   null.Int64
+	AccountSequence null.Int64
+	ChronoSequence  null.String
+	EventCode       null.String
+	JobID           null.String
+	TransactionDate null.Date
+	BackdateFlags
+	// Null when the original posting is not in the response
+	OriginalChronoSequence  null.String
+	OriginalAccountSequence null.Int64
+}
+
+// Paired reports whether the original posting of a was found
+func (a Adjustment) Paired() bool {
+	return a.OriginalChronoSequence.NotNull()
+}
+
+// ShiftDays is the number of days transaction_date lies after
+// original_transaction_date, null when either is missing
+func (a Adjustment) ShiftDays() null.Int64 {
+	if a.TransactionDate.Null() || a.OriginalTransactionDate.Null() {
+		return null.Int64{}
+	}
+	return null.NewInt64(daysBetween(a.OriginalTransactionDate.Val, a.TransactionDate.Val))
+}
+
+// AwaitingBackdate is an account whose latest is_awaiting_backdate is true
+type AwaitingBackdate struct {
+	AccountNumber null.Int64
+	Transactions  int
+	// Earliest original_transaction_date of the flagged transactions
+	OldestOriginalDate null.Date
+	// Days from OldestOriginalDate to the as-of date
+	AgeDays int64
+}
+
+// BackdateResult holds the adjustments in rs_body order and the accounts
+// awaiting back-date ordered oldest first
+type BackdateResult struct {
+	Adjustments []Adjustment
+	Awaiting    []AwaitingBackdate
+	Errors      []TransactionError
+}
+
+// Unpaired is the number of adjustments without their original posting
+func (r BackdateResult) Unpaired() int {
+	var n int
+	for _, a := range r.Adjustments {
+		if !a.Paired() {
+			n++
+		}
+	}
+	return n
+}
+
+// AnalyzeBackdates pairs the adjustments of body with their original
+// postings and ages the accounts awaiting back-date as of asOf
+func AnalyzeBackdates(body Body, opts Options, asOf date.Date) BackdateResult {
+	t := newBackdateTracker()
+	errs := eachTransaction(body, opts, t.add)
+	return t.result(errs, asOf)
+}
+
+// ReportBackdates analyzes the response read from r and writes the
+// adjustments to w. The awaiting accounts are returned for the caller to
+// write with AwaitingBackdateColumns.
+func ReportBackdates(r io.Reader, w io.Writer, opts Options, asOf date.Date) (BackdateResult, error) {
+	t := newBackdateTracker()
+	errs, err := Each(r, opts, t.add)
+	res := t.result(errs, asOf)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, AdjustmentColumns, res.Adjustments)
+}
+
+type backdateKey struct {
+	account int64
+	jobID   string
+}
+
+// backdateTracker collects adjustments and postings, pairing waits until
+// every posting of the job has been seen
+type backdateTracker struct {
+	adjustments []Adjustment
+	// postings with a positive account_sequence by account and job
+	postings map[backdateKey][]Transaction
+	// transactions with is_awaiting_backdate set, by account
+	flagged map[int64][]awaitingFlag
+}
+
+// awaitingFlag is the is_awaiting_backdate of one transaction
+type awaitingFlag struct {
+	chrono       string
+	awaiting     bool
+	originalDate null.Date
+}
+
+func newBackdateTracker() *backdateTracker {
+	return &backdateTracker{postings: map[backdateKey][]Transaction{}, flagged: map[int64][]awaitingFlag{}}
+}
+
+func (t *backdateTracker) add(tx Transaction) error {
+	var msg struct {
+		OtherProperties map[string]interface{} `json:"other_properties"`
+	}
+	if err := json.Unmarshal([]byte(tx.Message.String()), &msg); err != nil {
+		return fmt.Errorf("unmarshal %s message: %w", tx.EventCode, err)
+	}
+	flags, err := ParseBackdateFlags(msg.OtherProperties)
+	if err != nil {
+		return err
+	}
+
+	if flags.IsAwaitingBackdate.NotNull() {
+		t.flagged[tx.AccountNumber.Val] = append(t.flagged[tx.AccountNumber.Val], awaitingFlag{
+			chrono:       tx.ChronoSequence.String(),
+			awaiting:     flags.IsAwaitingBackdate.Val,
+			originalDate: flags.OriginalTransactionDate,
+		})
+	}
+
+	if tx.AccountSequence.Val >= 0 {
+		k := backdateKey{tx.AccountNumber.Val, tx.JobID.String()}
+		t.postings[k] = append(t.postings[k], tx)
+		return nil
+	}
+	a := Adjustment{
+		AccountNumber:   tx.AccountNumber,
+		AccountSequence: tx.AccountSequence,
+		ChronoSequence:  tx.ChronoSequence,
+		EventCode:       tx.EventCode,
+		JobID:           tx.JobID,
+		TransactionDate: tx.TransactionDate,
+		BackdateFlags:   flags,
+	}
+	t.adjustments = append(t.adjustments, a)
+	return nil
+}
+
+func (t *backdateTracker) result(errs []TransactionError, asOf date.Date) BackdateResult {
+	res := BackdateResult{Errors: errs}
+	// postings not yet the original of an adjustment
+	unused := make(map[backdateKey][]Transaction, len(t.postings))
+	for k, postings := range t.postings {
+		unused[k] = append([]Transaction(nil), postings...)
+	}
+	for _, a := range t.adjustments {
+		// only a posting of the same event code is the original, others of
+		// the job leave the adjustment unpaired
+		k := backdateKey{a.AccountNumber.Val, a.JobID.String()}
+		postings := unused[k]
+		for i, p := range postings {
+			if p.EventCode != a.EventCode {
+				continue
+			}
+			a.OriginalChronoSequence = p.ChronoSequence
+			a.OriginalAccountSequence = p.AccountSequence
+			unused[k] = append(postings[:i:i], postings[i+1:]...)
+			break
+		}
+		res.Adjustments = append(res.Adjustments, a)
+	}
+
+	for account, flags := range t.flagged {
+		// an account awaits back-date as long as its latest flag says so,
+		// counting the flagged transactions since it last cleared
+		sort.SliceStable(flags, func(i, j int) bool { return compareStrings(flags[i].chrono, flags[j].chrono) < 0 })
+		a := AwaitingBackdate{AccountNumber: null.NewInt64(account)}
+		for _, f := range flags {
+			if !f.awaiting {
+				a = AwaitingBackdate{AccountNumber: a.AccountNumber}
+				continue
+			}
+			a.Transactions++
+			if d := f.originalDate; d.NotNull() && (a.OldestOriginalDate.Null() || d.Val.Before(a.OldestOriginalDate.Val)) {
+				a.OldestOriginalDate = d
+			}
+		}
+		if a.Transactions == 0 {
+			continue
+		}
+		if a.OldestOriginalDate.NotNull() {
+			a.AgeDays = daysBetween(a.OldestOriginalDate.Val, asOf)
+		}
+		res.Awaiting = append(res.Awaiting, a)
+	}
+	sort.Slice(res.Awaiting, func(i, j int) bool {
+		a, b := res.Awaiting[i], res.Awaiting[j]
+		if a.AgeDays != b.AgeDays {
+			return a.AgeDays > b.AgeDays
+		}
+		return a.AccountNumber.Val < b.AccountNumber.Val
+	})
+	return res
+}
+
+// daysBetween is the number of calendar days from from to to
+func daysBetween(from, to date.Date) int64 {
+	return int64(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// AdjustmentColumns are the columns of the back-date adjustment report
+var AdjustmentColumns = []Column[Adjustment]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(a Adjustment) string { return int64Cell(a.AccountNumber) }},
+	{Name: "AccountSequence", Width: 8, Numeric: true, Value: func(a Adjustment) string { return int64Cell(a.AccountSequence) }},
+	{Name: "ChronoSequence", Width: 25, Value: func(a Adjustment) string { return stringCell(a.ChronoSequence) }},
+	{Name: "EventCode", Width: 10, Value: func(a Adjustment) string { return stringCell(a.EventCode) }},
+	{Name: "TransactionDate", Width: 10, Value: func(a Adjustment) string { return dateCell(a.TransactionDate) }},
+	{Name: "OriginalTransactionDate", Width: 10, Value: func(a Adjustment) string { return dateCell(a.OriginalTransactionDate) }},
+	{Name: "ShiftDays", Width: 6, Numeric: true, Value: func(a Adjustment) string { return int64Cell(a.ShiftDays()) }},
+	{Name: "IsAwaitingBackdate", Width: 5, Value: func(a Adjustment) string { return boolCell(a.IsAwaitingBackdate) }},
+	{Name: "AdjustmentFlag", Width: 5, Value: func(a Adjustment) string { return boolCell(a.AdjustmentFlag) }},
+	{Name: "OriginalAdjustmentFlag", Width: 5, Value: func(a Adjustment) string { return boolCell(a.OriginalAdjustmentFlag) }},
+	{Name: "Paired", Width: 5, Value: func(a Adjustment) string { return strconv.FormatBool(a.Paired()) }},
+	{Name: "OriginalChronoSequence", Width: 25, Value: func(a Adjustment) string { return stringCell(a.OriginalChronoSequence) }},
+	{Name: "OriginalAccountSequence", Width: 8, Numeric: true, Value: func(a Adjustment) string { return int64Cell(a.OriginalAccountSequence) }},
+}
+
+// AwaitingBackdateColumns are the columns of the awaiting back-date report
+var AwaitingBackdateColumns = []Column[AwaitingBackdate]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(a AwaitingBackdate) string { return int64Cell(a.AccountNumber) }},
+	{Name: "Transactions", Width: 6, Numeric: true, Value: func(a AwaitingBackdate) string { return strconv.Itoa(a.Transactions) }},
+	{Name: "OldestOriginalDate", Width: 10, Value: func(a AwaitingBackdate) string { return dateCell(a.OldestOriginalDate) }},
+	{Name: "AgeDays", Width: 6, Numeric: true, Value: func(a AwaitingBackdate) string { return strconv.FormatInt(a.AgeDays, 10) }},
+}
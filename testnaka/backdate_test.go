@@ -0,0 +1,116 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackdateFlags(t *testing.T) {
+	f, err := ParseBackdateFlags(map[string]interface{}{
+		"is_awaiting_backdate":      "true",
+		"original_transaction_date": "2024-12-16",
+		"adjustment_flag":           "",
+	})
+	assert.NoError(t, err)
+	assert.True(t, f.IsAwaitingBackdate.Equals(true))
+	assert.Equal(t, "2024-12-16", f.OriginalTransactionDate.String())
+	assert.True(t, f.AdjustmentFlag.Null())
+	assert.True(t, f.OriginalAdjustmentFlag.Null())
+
+	_, err = ParseBackdateFlags(map[string]interface{}{"original_transaction_date": "16/12/2024"})
+	assert.Error(t, err)
+	_, err = ParseBackdateFlags(map[string]interface{}{"is_awaiting_backdate": true})
+	assert.Error(t, err)
+}
+
+func TestReportBackdates(t *testing.T) {
+	asOf, _ := date.NewDates("2025-02-13")
+	var out bytes.Buffer
+	res, err := ReportBackdates(openFixture(t), &out, DefaultOptions(), asOf)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+
+	originals := map[string]bool{}
+	for _, a := range res.Adjustments {
+		assert.Less(t, a.AccountSequence.Val, int64(0))
+		if a.Paired() {
+			// a posting is the original of one adjustment only
+			assert.False(t, originals[a.OriginalChronoSequence.String()])
+			originals[a.OriginalChronoSequence.String()] = true
+			assert.GreaterOrEqual(t, a.OriginalAccountSequence.Val, int64(0))
+		}
+	}
+	assert.Equal(t, 154, len(res.Adjustments))
+	// most due_bills adjustments share their job with fee postings only
+	assert.Len(t, originals, 6)
+	assert.Equal(t, 148, res.Unpaired())
+	assert.Equal(t, 155, strings.Count(out.String(), "\n"))
+
+	// 190000098802 paid a fee and others alongside its due_bills adjustments
+	assert.Contains(t, out.String(), "190000098802|-108|250115")
+	for _, a := range res.Adjustments {
+		if a.AccountNumber.Val == 190000098802 && a.EventCode.String() == "others" {
+			assert.True(t, a.Paired())
+		}
+		if a.AccountNumber.Val == 190000098802 && a.EventCode.String() == "due_bills" {
+			// the fee posting of the job is not its original
+			assert.True(t, a.OriginalChronoSequence.Null())
+			assert.Equal(t, int64(1), a.ShiftDays().Val)
+		}
+	}
+
+	if assert.NotEmpty(t, res.Awaiting) {
+		assert.Equal(t, "2025-01-14", res.Awaiting[0].OldestOriginalDate.String())
+		assert.Equal(t, int64(30), res.Awaiting[0].AgeDays)
+	}
+
+	// bill-generation entries carry the oldest original dates
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	res = AnalyzeBackdates(body, Options{}, asOf)
+	if assert.NotEmpty(t, res.Awaiting) {
+		oldest := res.Awaiting[0]
+		assert.Equal(t, "2024-10-15", oldest.OldestOriginalDate.String())
+		assert.Equal(t, int64(121), oldest.AgeDays)
+		for _, a := range res.Awaiting[1:] {
+			assert.LessOrEqual(t, a.AgeDays, oldest.AgeDays)
+		}
+	}
+}
+
+func TestAnalyzeBackdates_latestFlag(t *testing.T) {
+	tx := func(account int64, chrono, awaiting, original string) Transaction {
+		return Transaction{
+			AccountNumber:   null.NewInt64(account),
+			AccountSequence: null.NewInt64(1),
+			ChronoSequence:  null.NewString(chrono),
+			EventCode:       null.NewString("due_bills"),
+			Message:         null.NewString(`{"other_properties":{"is_awaiting_backdate":"` + awaiting + `","original_transaction_date":"` + original + `"}}`),
+		}
+	}
+	body := Body{ReqBody: []Transaction{
+		// cleared by a later transaction, whatever the rs_body order
+		tx(1, "250115000003", "false", ""),
+		tx(1, "250115000001", "true", "2025-01-10"),
+		// awaiting again after clearing, the cleared one does not count
+		tx(2, "250115000001", "true", "2025-01-05"),
+		tx(2, "250115000002", "false", ""),
+		tx(2, "250115000003", "true", "2025-01-12"),
+		tx(2, "250115000004", "", ""),
+	}}
+	asOf, _ := date.NewDates("2025-01-15")
+	res := AnalyzeBackdates(body, Options{}, asOf)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Awaiting, 1) {
+		a := res.Awaiting[0]
+		assert.Equal(t, int64(2), a.AccountNumber.Val)
+		assert.Equal(t, 1, a.Transactions)
+		assert.Equal(t, "2025-01-12", a.OldestOriginalDate.String())
+		assert.Equal(t, int64(3), a.AgeDays)
+	}
+}
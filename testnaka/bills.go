@@ -0,0 +1,153 @@
+package testnaka
+
+import (
+	"context"
+	"io"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Kinds of BillRow
+const (
+	BillKindBill    = "bill"
+	BillKindPenalty = "penalty"
+	BillKindFee     = "fee"
+)
+
+// BillRow is one bill, penalty or fee entry of a transaction, keyed like
+// ^Z8804dbill by account number and bill sequence
+type BillRow struct {
+	AccountNumber   null.Int64
+	AccountSequence null.Int64
+	ChronoSequence  null.String
+	EventCode       null.String
+	Kind            string
+	BillSequence    null.Int64
+	// loan_due_date for fee entries
+	BillDueDate           null.String
+	PrincipalAmount       null.Dec2
+	InterestAmount        null.Dec2
+	PenaltyAmount         null.Dec2
+	VatAmount             null.Dec2
+	UnpaidPrincipalAmount null.Dec2
+	UnpaidInterestAmount  null.Dec2
+	UnpaidPenaltyAmount   null.Dec2
+	UnpaidVatAmount       null.Dec2
+	FeeAmount             null.Dec2
+}
+
+// BillResult holds the exploded rows and the transactions that failed
+type BillResult struct {
+	Rows   []BillRow
+	Errors []TransactionError
+}
+
+// ExtractBills reads a publishMessageDetail response and returns one row per bill, penalty and fee
+func ExtractBills(r io.Reader, opts Options) (BillResult, error) {
+	var res BillResult
+	errs, err := Each(r, opts, func(tx Transaction) error {
+		rows, err := opts.registry().ExplodeTransaction(tx)
+		res.Rows = append(res.Rows, rows...)
+		return err
+	})
+	res.Errors = errs
+	return res, err
+}
+
+// ReportBills streams bill rows from r to w as they are decoded and returns
+// the transactions that failed
+func ReportBills(r io.Reader, w io.Writer, opts Options) ([]TransactionError, error) {
+	return ReportBillsContext(context.Background(), r, w, opts)
+}
+
+// ReportBillsContext is ReportBills stopping when ctx is done
+func ReportBillsContext(ctx context.Context, r io.Reader, w io.Writer, opts Options) ([]TransactionError, error) {
+	tw, err := NewTableWriter(w, opts.Output, BillRowColumns)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := EachOrdered(ctx, r, opts, opts.registry().ExplodeTransaction, func(rows []BillRow) error {
+		for _, row := range rows {
+			if err := tw.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errs, err
+	}
+	return errs, tw.Flush()
+}
+
+// ProcessBodyBills explodes every transaction of body
+func ProcessBodyBills(body Body, opts Options) BillResult {
+	var res BillResult
+	res.Errors = eachTransaction(body, opts, func(tx Transaction) error {
+		rows, err := opts.registry().ExplodeTransaction(tx)
+		res.Rows = append(res.Rows, rows...)
+		return err
+	})
+	return res
+}
+
+// ExplodeTransaction returns the bill, penalty and fee entries of tx with
+// the handler of its event code in DefaultRegistry
+func ExplodeTransaction(tx Transaction) ([]BillRow, error) {
+	return DefaultRegistry.ExplodeTransaction(tx)
+}
+
+func (r BillRow) withBill(b Bill) BillRow {
+	r.Kind = BillKindBill
+	r.BillSequence = b.BillSequence
+	r.BillDueDate = b.BillDueDate
+	r.PrincipalAmount = b.PrincipalAmount
+	r.InterestAmount = b.InterestAmount
+	r.PenaltyAmount = b.PenaltyAmount
+	r.VatAmount = b.VatAmount
+	r.UnpaidPrincipalAmount = b.UnpaidPrincipalAmount
+	r.UnpaidInterestAmount = b.UnpaidInterestAmount
+	r.UnpaidPenaltyAmount = b.UnpaidPenaltyAmount
+	r.UnpaidVatAmount = b.UnpaidVatAmount
+	return r
+}
+
+func (r BillRow) withPenalty(p Penalty) BillRow {
+	r.Kind = BillKindPenalty
+	r.BillSequence = p.BillSequence
+	r.BillDueDate = p.BillDueDate
+	r.PenaltyAmount = p.PenaltyAmount
+	return r
+}
+
+func (r BillRow) withFee(f Fee) BillRow {
+	r.Kind = BillKindFee
+	r.BillSequence = f.BillSequence
+	r.BillDueDate = f.LoanDueDate
+	r.FeeAmount = f.FeeAmount
+	return r
+}
+
+// BillRowColumns are the columns of the bill report
+var BillRowColumns = []Column[BillRow]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(r BillRow) string { return int64Cell(r.AccountNumber) }},
+	{Name: "AccountSequence", Width: 8, Numeric: true, Value: func(r BillRow) string { return int64Cell(r.AccountSequence) }},
+	{Name: "EventCode", Width: 10, Value: func(r BillRow) string { return stringCell(r.EventCode) }},
+	{Name: "Kind", Width: 7, Value: func(r BillRow) string { return r.Kind }},
+	{Name: "BillSequence", Width: 6, Numeric: true, Value: func(r BillRow) string { return int64Cell(r.BillSequence) }},
+	{Name: "BillDueDate", Width: 10, Value: func(r BillRow) string { return stringCell(r.BillDueDate) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.PrincipalAmount) }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.InterestAmount) }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.PenaltyAmount) }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.VatAmount) }},
+	{Name: "UnpaidPrincipalAmount", Width: 21, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.UnpaidPrincipalAmount) }},
+	{Name: "UnpaidInterestAmount", Width: 20, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.UnpaidInterestAmount) }},
+	{Name: "UnpaidPenaltyAmount", Width: 19, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.UnpaidPenaltyAmount) }},
+	{Name: "UnpaidVatAmount", Width: 15, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.UnpaidVatAmount) }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(r BillRow) string { return dec2Cell(r.FeeAmount) }},
+}
+
+// WriteBillRows writes rows as the pipe-delimited bill report, header first
+func WriteBillRows(w io.Writer, rows []BillRow) error {
+	return WriteTable(w, WriteOptions{Format: FormatPipe}, BillRowColumns, rows)
+}
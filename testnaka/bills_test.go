@@ -0,0 +1,55 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplodeTransaction(t *testing.T) {
+	tx := Transaction{
+		AccountNumber:   null.NewInt64(190000003836),
+		AccountSequence: null.NewInt64(-108),
+		EventCode:       null.NewString("due_bills"),
+		Message: null.NewString(`{"principal_amount":3766.83,"other_properties":{` +
+			`"bills":"[{\"bill_sequence\":45,\"bill_due_date\":\"2024-10-10\",\"principal_amount\":3766.83,\"interest_amount\":128.50,\"penalty_amount\":0.00,\"vat_amount\":272.67,\"unpaid_principal_amount\":1.00,\"unpaid_interest_amount\":0.00,\"unpaid_penalty_amount\":0.00,\"unpaid_vat_amount\":0.00}]",` +
+			`"penalties":"[{\"bill_sequence\":46,\"bill_due_date\":\"2024-11-10\",\"penalty_amount\":5.00}]"}}`),
+	}
+	rows, err := ExplodeTransaction(tx)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, BillKindBill, rows[0].Kind)
+		assert.Equal(t, "45", rows[0].BillSequence.String())
+		assert.Equal(t, "-108", rows[0].AccountSequence.String())
+		assert.Equal(t, "1.00", rows[0].UnpaidPrincipalAmount.String())
+		assert.Equal(t, BillKindPenalty, rows[1].Kind)
+		assert.Equal(t, "5.00", rows[1].PenaltyAmount.String())
+		assert.True(t, rows[1].PrincipalAmount.Null())
+	}
+}
+
+func TestReportBills_fixture(t *testing.T) {
+	var out bytes.Buffer
+	errs, err := ReportBills(openFixture(t), &out, DefaultOptions())
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+
+	res, err := ExtractBills(openFixture(t), DefaultOptions())
+	assert.NoError(t, err)
+
+	kinds := map[string]int{}
+	for _, row := range res.Rows {
+		kinds[row.Kind]++
+		assert.True(t, row.BillSequence.NotNull())
+	}
+	assert.Greater(t, kinds[BillKindBill], 0)
+	assert.Greater(t, kinds[BillKindPenalty], 0)
+	assert.Greater(t, kinds[BillKindFee], 0)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, "AccountNumber|AccountSequence|EventCode|Kind|BillSequence|BillDueDate|PrincipalAmount|InterestAmount|PenaltyAmount|VatAmount|UnpaidPrincipalAmount|UnpaidInterestAmount|UnpaidPenaltyAmount|UnpaidVatAmount|FeeAmount", lines[0])
+	assert.Equal(t, "190000003836|-108|due_bills|bill|45|2024-10-10|3766.83|128.50|0.00|272.67|0.00|0.00|0.00|0.00|", lines[1])
+}
@@ -0,0 +1,321 @@
+// Package client calls the query-dloan-payment publishMessageDetail query
+// and returns the response the testnaka reports read.
+//
+//	c := client.New(client.Config{BaseURL: "http://query-dloan-payment:8080", AuditDir: "audit"})
+//	body, err := c.Fetch(ctx, client.Query{AccountNumbers: []int64{190000003836}})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/apptime"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka"
+)
+
+// Path of the publishMessageDetail query
+const DefaultPath = "/query-dloan-payment/v1/publishMessageDetail"
+
+// DefaultMaxPages caps the pages fetched per window
+const DefaultMaxPages = 10000
+
+// Query parameters of publishMessageDetail
+const (
+	ParamAccountNumber = "account_number"
+	ParamDateFrom      = "transaction_date_from"
+	ParamDateTo        = "transaction_date_to"
+	ParamJobID         = "job_id"
+	ParamEventCode     = "event_code"
+	ParamPage          = "page"
+	ParamPageSize      = "page_size"
+)
+
+// Query selects the transactions to fetch, empty criteria match everything
+type Query struct {
+	AccountNumbers []int64
+	// Inclusive transaction_date range, a null bound is open
+	DateFrom   null.Date
+	DateTo     null.Date
+	JobIDs     []string
+	EventCodes []string
+}
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	BaseURL string
+	// Defaults to DefaultPath
+	Path string
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Transactions per page, 0 fetches each window in one request. A page
+	// shorter than PageSize, empty or the same as the one before is the last
+	// one, the last covers servers that ignore paging.
+	PageSize int
+	// Days per request when both dates are set, 0 does not split the range
+	WindowDays int
+	// Retries after a 5xx, a 429, a timeout or a transport error
+	MaxRetries int
+	// Wait before the first retry, doubled for each one after. Defaults to 500ms.
+	Backoff time.Duration
+	// Directory raw responses are written to, none when empty
+	AuditDir string
+	// Pages fetched per window before Fetch fails, defaults to DefaultMaxPages
+	MaxPages int
+}
+
+// Client fetches publishMessageDetail responses. It is safe for concurrent use.
+type Client struct {
+	cfg Config
+
+	// run starts the audit file names of the client, so runs into the same
+	// AuditDir keep apart
+	run string
+
+	mu    sync.Mutex
+	audit int
+}
+
+// New returns a client for cfg
+func New(cfg Config) *Client {
+	if cfg.Path == "" {
+		cfg.Path = DefaultPath
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 500 * time.Millisecond
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = DefaultMaxPages
+	}
+	return &Client{cfg: cfg, run: apptime.Now().Format("20060102T150405.000000000")}
+}
+
+// StatusError is a response other than 200 OK
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("publishMessageDetail: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Fetch returns the transactions matching q, window after window and page
+// after page in the order the server returns them
+func (c *Client) Fetch(ctx context.Context, q Query) (testnaka.Body, error) {
+	var body testnaka.Body
+	windows, err := Windows(q.DateFrom, q.DateTo, c.cfg.WindowDays)
+	if err != nil {
+		return body, err
+	}
+	for _, w := range windows {
+		wq := q
+		wq.DateFrom, wq.DateTo = w.From, w.To
+		var prev []byte
+		for page := 1; ; page++ {
+			if page > c.cfg.MaxPages {
+				return body, fmt.Errorf("publishMessageDetail: more than %d pages from %s", c.cfg.MaxPages, c.URL(wq, 1))
+			}
+			txs, err := c.fetchPage(ctx, wq, page)
+			if err != nil {
+				return body, err
+			}
+			if c.cfg.PageSize <= 0 {
+				body.ReqBody = append(body.ReqBody, txs...)
+				break
+			}
+			if len(txs) == 0 {
+				break
+			}
+			// a server that ignores page returns the first page again
+			encoded, err := json.Marshal(txs)
+			if err != nil {
+				return body, err
+			}
+			if bytes.Equal(encoded, prev) {
+				break
+			}
+			prev = encoded
+			body.ReqBody = append(body.ReqBody, txs...)
+			if len(txs) < c.cfg.PageSize {
+				break
+			}
+		}
+	}
+	return body, nil
+}
+
+// Window is an inclusive transaction_date range, a null bound is open
+type Window struct {
+	From null.Date
+	To   null.Date
+}
+
+// Windows splits from..to into consecutive windows of at most days days.
+// An open range or days <= 0 gives the range itself, from after to an error.
+func Windows(from, to null.Date, days int) ([]Window, error) {
+	if from.NotNull() && to.NotNull() && from.Val.After(to.Val) {
+		return nil, fmt.Errorf("publishMessageDetail: %s %s is after %s %s", ParamDateFrom, from, ParamDateTo, to)
+	}
+	if days <= 0 || from.Null() || to.Null() {
+		return []Window{{From: from, To: to}}, nil
+	}
+	var windows []Window
+	for start := from.Val; !start.After(to.Val); {
+		end := start.AddDate(0, 0, days-1)
+		if end.After(to.Val) {
+			end = to.Val
+		}
+		windows = append(windows, Window{From: null.NewDate(start), To: null.NewDate(end)})
+		start = end.AddDate(0, 0, 1)
+	}
+	return windows, nil
+}
+
+// URL returns the request of page of q, page is ignored without a page size
+func (c *Client) URL(q Query, page int) string {
+	v := url.Values{}
+	for _, a := range q.AccountNumbers {
+		v.Add(ParamAccountNumber, strconv.FormatInt(a, 10))
+	}
+	if q.DateFrom.NotNull() {
+		v.Set(ParamDateFrom, q.DateFrom.String())
+	}
+	if q.DateTo.NotNull() {
+		v.Set(ParamDateTo, q.DateTo.String())
+	}
+	for _, j := range q.JobIDs {
+		v.Add(ParamJobID, j)
+	}
+	for _, e := range q.EventCodes {
+		v.Add(ParamEventCode, e)
+	}
+	if c.cfg.PageSize > 0 {
+		v.Set(ParamPage, strconv.Itoa(page))
+		v.Set(ParamPageSize, strconv.Itoa(c.cfg.PageSize))
+	}
+	u := c.cfg.BaseURL + c.cfg.Path
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+	return u
+}
+
+func (c *Client) fetchPage(ctx context.Context, q Query, page int) ([]testnaka.Transaction, error) {
+	raw, err := c.get(ctx, c.URL(q, page))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writeAudit(q, page, raw); err != nil {
+		return nil, err
+	}
+	body, err := testnaka.DecodeBody(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return body.ReqBody, nil
+}
+
+// get returns the body of a 200 response, retrying the errors retryable accepts
+func (c *Client) get(ctx context.Context, u string) ([]byte, error) {
+	wait := c.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		raw, err := c.getOnce(ctx, u)
+		if err == nil || attempt >= c.cfg.MaxRetries || !retryable(ctx, err) {
+			return raw, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) getOnce(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("publishMessageDetail: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(raw))}
+	}
+	return raw, nil
+}
+
+// retryable reports whether err is a 5xx, a 429, a timeout or a transport
+// error while ctx is alive. Cancellations and malformed requests are final.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+	}
+	// url.Error, what the http.Client returns, is a net.Error
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// writeAudit writes raw to AuditDir as <run>_<n>_<from>_<to>_page<page>.json,
+// numbered in the order responses arrived. An existing file is never
+// overwritten.
+func (c *Client) writeAudit(q Query, page int, raw []byte) error {
+	if c.cfg.AuditDir == "" {
+		return nil
+	}
+	c.mu.Lock()
+	c.audit++
+	n := c.audit
+	c.mu.Unlock()
+	if err := os.MkdirAll(c.cfg.AuditDir, 0o755); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	name := fmt.Sprintf("%s_%04d_%s_%s_page%03d.json", c.run, n, auditDate(q.DateFrom), auditDate(q.DateTo), page)
+	f, err := os.OpenFile(filepath.Join(c.cfg.AuditDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("audit: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+func auditDate(d null.Date) string {
+	if d.Null() {
+		return "open"
+	}
+	return d.String()
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka"
+	"github.com/stretchr/testify/assert"
+)
+
+var fixtureFile = filepath.Join("..", testnaka.ResponseFile)
+
+// fixtureServer serves the fixture selected by ParseRequest. failures 5xx
+// responses are returned first.
+type fixtureServer struct {
+	t        *testing.T
+	body     testnaka.Body
+	mu       sync.Mutex
+	failures int
+	// serve every match whatever page and page_size say
+	ignorePaging bool
+	requests     []string
+}
+
+func newFixtureServer(t *testing.T) (*fixtureServer, *httptest.Server) {
+	file, err := os.Open(fixtureFile)
+	assert.NoError(t, err)
+	defer file.Close()
+	body, err := testnaka.DecodeBody(file)
+	assert.NoError(t, err)
+	s := &fixtureServer{t: t, body: body}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *fixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RawQuery)
+	fail := s.failures > 0
+	if fail {
+		s.failures--
+	}
+	s.mu.Unlock()
+	if fail {
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Path != DefaultPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	req, err := ParseRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.ignorePaging {
+		req.PageSize = 0
+	}
+	out := testnaka.Body{ReqBody: req.Select(s.body.ReqBody)}
+	w.Header().Set("Content-Type", "application/json")
+	assert.NoError(s.t, testnaka.EncodeBody(w, out))
+}
+
+func TestFetch_pages(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	c := New(Config{BaseURL: srv.URL, PageSize: 100})
+
+	body, err := c.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 258)
+	assert.Equal(t, s.body.ReqBody, body.ReqBody)
+	assert.Equal(t, []string{
+		"page=1&page_size=100",
+		"page=2&page_size=100",
+		"page=3&page_size=100",
+	}, s.requests)
+}
+
+func TestFetch_serverIgnoresPaging(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	s.ignorePaging = true
+	c := New(Config{BaseURL: srv.URL, PageSize: 100})
+
+	body, err := c.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, s.body.ReqBody, body.ReqBody)
+	assert.Len(t, s.requests, 2)
+
+	// pages that differ every time stop at the cap
+	s.requests = nil
+	s.ignorePaging = false
+	c = New(Config{BaseURL: srv.URL, PageSize: 1, MaxPages: 3})
+	body, err = c.Fetch(context.Background(), Query{})
+	assert.Error(t, err)
+	assert.Len(t, body.ReqBody, 3)
+	assert.Len(t, s.requests, 3)
+}
+
+func TestFetch_emptyPage(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	c := New(Config{BaseURL: srv.URL, PageSize: 129})
+
+	body, err := c.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 258)
+	assert.Len(t, s.requests, 3)
+}
+
+func TestFetch_filters(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	c := New(Config{BaseURL: srv.URL})
+
+	body, err := c.Fetch(context.Background(), Query{AccountNumbers: []int64{190000003836}, JobIDs: []string{"250115bc5457fdCD909972"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, body.ReqBody)
+	for _, tx := range body.ReqBody {
+		assert.Equal(t, int64(190000003836), tx.AccountNumber.Val)
+		assert.Equal(t, "250115bc5457fdCD909972", tx.JobID.String())
+	}
+	assert.Equal(t, []string{"account_number=190000003836&job_id=250115bc5457fdCD909972"}, s.requests)
+}
+
+func TestFetch_windows(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	c := New(Config{BaseURL: srv.URL, WindowDays: 7})
+	from, _ := null.NewDates("2025-01-01")
+	to, _ := null.NewDates("2025-01-20")
+
+	body, err := c.Fetch(context.Background(), Query{DateFrom: from, DateTo: to})
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 258)
+	assert.Equal(t, []string{
+		"transaction_date_from=2025-01-01&transaction_date_to=2025-01-07",
+		"transaction_date_from=2025-01-08&transaction_date_to=2025-01-14",
+		"transaction_date_from=2025-01-15&transaction_date_to=2025-01-20",
+	}, s.requests)
+}
+
+func TestWindows(t *testing.T) {
+	from, _ := null.NewDates("2025-01-30")
+	to, _ := null.NewDates("2025-02-01")
+	windows, err := Windows(from, to, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []Window{{From: from, To: to}}, windows)
+	windows, err = Windows(null.Date{}, to, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Window{{To: to}}, windows)
+
+	_, err = Windows(to, from, 1)
+	assert.Error(t, err)
+	_, err = Windows(to, from, 0)
+	assert.Error(t, err)
+	_, err = New(Config{BaseURL: "http://unused"}).Fetch(context.Background(), Query{DateFrom: to, DateTo: from})
+	assert.Error(t, err)
+
+	windows, err = Windows(from, to, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, windows, 3) {
+		assert.Equal(t, "2025-01-31", windows[1].From.String())
+		assert.Equal(t, "2025-02-01", windows[2].To.String())
+	}
+}
+
+func TestFetch_retry(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	s.failures = 2
+	c := New(Config{BaseURL: srv.URL, MaxRetries: 2, Backoff: time.Millisecond})
+
+	body, err := c.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 258)
+	assert.Len(t, s.requests, 3)
+
+	s.requests = nil
+	s.failures = 3
+	_, err = c.Fetch(context.Background(), Query{})
+	var status *StatusError
+	if assert.True(t, errors.As(err, &status)) {
+		assert.Equal(t, http.StatusServiceUnavailable, status.StatusCode)
+	}
+	assert.Len(t, s.requests, 3)
+}
+
+func TestFetch_noRetryOn4xx(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	c := New(Config{BaseURL: srv.URL, Path: "/missing", MaxRetries: 3, Backoff: time.Millisecond})
+
+	_, err := c.Fetch(context.Background(), Query{})
+	var status *StatusError
+	if assert.True(t, errors.As(err, &status)) {
+		assert.Equal(t, http.StatusNotFound, status.StatusCode)
+	}
+	assert.Len(t, s.requests, 1)
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+	transport := &url.Error{Op: "Get", URL: "http://x", Err: errors.New("connection refused")}
+	for err, want := range map[error]bool{
+		transport:                                true,
+		&StatusError{StatusCode: 503}:            true,
+		&StatusError{StatusCode: 429}:            true,
+		&StatusError{StatusCode: 404}:            false,
+		io.ErrUnexpectedEOF:                      true,
+		errors.New("invalid character"):          false,
+		&url.Error{Err: context.Canceled}:        false,
+		fmt.Errorf("read: %w", context.Canceled): false,
+	} {
+		assert.Equal(t, want, retryable(ctx, err), err.Error())
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, retryable(canceled, transport))
+}
+
+func TestFetch_canceledDuringBackoff(t *testing.T) {
+	s, srv := newFixtureServer(t)
+	s.failures = 1
+	c := New(Config{BaseURL: srv.URL, MaxRetries: 1, Backoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Fetch(ctx, Query{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFetch_audit(t *testing.T) {
+	_, srv := newFixtureServer(t)
+	dir := t.TempDir()
+	c := New(Config{BaseURL: srv.URL, PageSize: 200, AuditDir: dir})
+
+	_, err := c.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if assert.Len(t, names, 2) {
+		assert.True(t, strings.HasSuffix(names[0], "_0001_open_open_page001.json"), names[0])
+		assert.True(t, strings.HasSuffix(names[1], "_0002_open_open_page002.json"), names[1])
+	}
+
+	// a second run into the directory keeps the first one's files
+	c2 := New(Config{BaseURL: srv.URL, PageSize: 200, AuditDir: dir})
+	c2.run = c.run + "b"
+	_, err = c2.Fetch(context.Background(), Query{})
+	assert.NoError(t, err)
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	// nor does a run that would reuse a name
+	c2 = New(Config{BaseURL: srv.URL, PageSize: 200, AuditDir: dir})
+	c2.run = c.run
+	_, err = c2.Fetch(context.Background(), Query{})
+	assert.Error(t, err)
+
+	raw, err := os.ReadFile(filepath.Join(dir, names[1]))
+	assert.NoError(t, err)
+	body, err := testnaka.DecodeBody(bytes.NewReader(raw))
+	assert.NoError(t, err)
+	assert.Len(t, body.ReqBody, 58)
+}
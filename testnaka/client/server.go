@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka"
+)
+
+// Request is a publishMessageDetail request as a server reads it, the
+// reverse of Client.URL. Fakes of the service select with it so they agree
+// with the client on the meaning of every parameter.
+type Request struct {
+	Query
+	// Page starting at 1, ignored without a page size
+	Page int
+	// Transactions per page, 0 serves every match
+	PageSize int
+}
+
+// ParseRequest parses the query parameters of a request
+func ParseRequest(v url.Values) (Request, error) {
+	var req Request
+	for _, a := range v[ParamAccountNumber] {
+		n, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return req, fmt.Errorf("invalid %s %q", ParamAccountNumber, a)
+		}
+		req.AccountNumbers = append(req.AccountNumbers, n)
+	}
+	req.JobIDs = v[ParamJobID]
+	req.EventCodes = v[ParamEventCode]
+	var err error
+	if req.DateFrom, err = parseDate(v, ParamDateFrom); err != nil {
+		return req, err
+	}
+	if req.DateTo, err = parseDate(v, ParamDateTo); err != nil {
+		return req, err
+	}
+	if req.PageSize, err = parseInt(v, ParamPageSize, 0); err != nil {
+		return req, err
+	}
+	if req.Page, err = parseInt(v, ParamPage, 1); err != nil {
+		return req, err
+	}
+	if req.Page < 1 {
+		return req, fmt.Errorf("invalid %s %d: pages start at 1", ParamPage, req.Page)
+	}
+	if req.PageSize > 0 && req.Page-1 > math.MaxInt/req.PageSize {
+		return req, fmt.Errorf("invalid %s %d: past the last transaction of any response", ParamPage, req.Page)
+	}
+	return req, nil
+}
+
+// Filter returns the filter of the criteria of q
+func (q Query) Filter() testnaka.Filter {
+	return testnaka.Filter{
+		AccountNumbers: q.AccountNumbers,
+		EventCodes:     q.EventCodes,
+		JobIDs:         q.JobIDs,
+		DateFrom:       q.DateFrom,
+		DateTo:         q.DateTo,
+	}
+}
+
+// Select returns the transactions of txs matching req, paged
+func (req Request) Select(txs []testnaka.Transaction) []testnaka.Transaction {
+	f := req.Filter()
+	var out []testnaka.Transaction
+	for _, tx := range txs {
+		if f.Match(tx) {
+			out = append(out, tx)
+		}
+	}
+	if req.PageSize <= 0 {
+		return out
+	}
+	start := (req.Page - 1) * req.PageSize
+	if start < 0 || start >= len(out) {
+		return nil
+	}
+	end := len(out)
+	if req.PageSize < end-start {
+		end = start + req.PageSize
+	}
+	return out[start:end]
+}
+
+func parseDate(v url.Values, name string) (null.Date, error) {
+	s := v.Get(name)
+	if s == "" {
+		return null.Date{}, nil
+	}
+	d, err := null.NewDates(s)
+	if err != nil {
+		return d, fmt.Errorf("invalid %s %q: want yyyy-mm-dd", name, s)
+	}
+	return d, nil
+}
+
+func parseInt(v url.Values, name string, def int) (int, error) {
+	s := v.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return n, nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/note/testnaka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequest(t *testing.T) {
+	from, _ := null.NewDates("2025-01-01")
+	q := Query{AccountNumbers: []int64{1, 2}, DateFrom: from, JobIDs: []string{"j"}, EventCodes: []string{"fee"}}
+	u, err := url.Parse(New(Config{BaseURL: "http://x", PageSize: 50}).URL(q, 3))
+	assert.NoError(t, err)
+	req, err := ParseRequest(u.Query())
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Query: q, Page: 3, PageSize: 50}, req)
+
+	for _, raw := range []string{"account_number=x", "transaction_date_to=15/01/2025", "page_size=-1", "page=0", "page=4611686018427387904&page_size=4"} {
+		v, _ := url.ParseQuery(raw)
+		_, err := ParseRequest(v)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestRequest_Select(t *testing.T) {
+	var txs []testnaka.Transaction
+	for i := 0; i < 5; i++ {
+		txs = append(txs, testnaka.Transaction{AccountNumber: null.NewInt64(int64(i % 2)), ChronoSequence: null.NewString(strconv.Itoa(i))})
+	}
+	assert.Len(t, Request{}.Select(txs), 5)
+	assert.Len(t, Request{Query: Query{AccountNumbers: []int64{0}}}.Select(txs), 3)
+
+	page := Request{Page: 2, PageSize: 2}.Select(txs)
+	if assert.Len(t, page, 2) {
+		assert.Equal(t, "2", page[0].ChronoSequence.String())
+	}
+	assert.Len(t, Request{Page: 3, PageSize: 2}.Select(txs), 1)
+	assert.Empty(t, Request{Page: 4, PageSize: 2}.Select(txs))
+}
@@ -0,0 +1,88 @@
+package testnaka
+
+import (
+	"io"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Finding is a message amount that does not equal the sum of its nested entries
+type Finding struct {
+	ChronoSequence  null.String
+	AccountNumber   null.Int64
+	AccountSequence null.Int64
+	EventCode       null.String
+	// Message key of the amount, e.g. principal_amount
+	Field         string
+	MessageAmount decimal.Dec2
+	NestedAmount  decimal.Dec2
+}
+
+// Delta is the message amount minus the nested sum
+func (f Finding) Delta() decimal.Dec2 {
+	return f.MessageAmount.Sub(f.NestedAmount)
+}
+
+// ConsistencyResult holds the findings of a consistency run
+type ConsistencyResult struct {
+	Checked  int
+	Findings []Finding
+	Errors   []TransactionError
+}
+
+// CheckBodyConsistency checks every kept transaction of body
+func CheckBodyConsistency(body Body, opts Options) ConsistencyResult {
+	var res ConsistencyResult
+	res.Errors = eachTransaction(body, opts, res.checker(opts))
+	return res
+}
+
+// checker returns a func adding the findings of a transaction to res
+func (res *ConsistencyResult) checker(opts Options) func(tx Transaction) error {
+	reg := opts.registry()
+	return func(tx Transaction) error {
+		findings, err := reg.CheckConsistency(tx)
+		if err != nil {
+			return err
+		}
+		res.Checked++
+		res.Findings = append(res.Findings, findings...)
+		return nil
+	}
+}
+
+// ReportConsistency checks the response read from r and writes the findings to w
+func ReportConsistency(r io.Reader, w io.Writer, opts Options) (ConsistencyResult, error) {
+	var res ConsistencyResult
+	errs, err := Each(r, opts, res.checker(opts))
+	res.Errors = errs
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, FindingColumns, res.Findings)
+}
+
+// CheckConsistency compares the message amounts of tx with its nested
+// arrays with the handler of its event code in DefaultRegistry:
+//
+//	due_bills  principal, interest and vat against bills,
+//	           penalty against bills plus penalties
+//	fee        fee against fee
+//	others     principal and interest against advance_payment,
+//	           penalty against advance_payment plus penalties
+func CheckConsistency(tx Transaction) ([]Finding, error) {
+	return DefaultRegistry.CheckConsistency(tx)
+}
+
+// FindingColumns are the columns of the consistency findings report
+var FindingColumns = []Column[Finding]{
+	{Name: "ChronoSequence", Width: 25, Value: func(f Finding) string { return stringCell(f.ChronoSequence) }},
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(f Finding) string { return int64Cell(f.AccountNumber) }},
+	{Name: "AccountSequence", Width: 8, Numeric: true, Value: func(f Finding) string { return int64Cell(f.AccountSequence) }},
+	{Name: "EventCode", Width: 10, Value: func(f Finding) string { return stringCell(f.EventCode) }},
+	{Name: "Field", Width: 16, Value: func(f Finding) string { return f.Field }},
+	{Name: "MessageAmount", Width: 15, Numeric: true, Value: func(f Finding) string { return f.MessageAmount.String() }},
+	{Name: "NestedAmount", Width: 15, Numeric: true, Value: func(f Finding) string { return f.NestedAmount.String() }},
+	{Name: "Delta", Width: 15, Numeric: true, Value: func(f Finding) string { return f.Delta().String() }},
+}
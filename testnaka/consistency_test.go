@@ -0,0 +1,52 @@
+package testnaka
+
+import (
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	tx := Transaction{
+		ChronoSequence: null.NewString("C1"),
+		EventCode:      null.NewString("due_bills"),
+		Message: null.NewString(`{"principal_amount":100.00,"interest_amount":10.00,"penalty_amount":3.00,"vat_amount":0.70,"other_properties":{` +
+			`"bills":"[{\"principal_amount\":60.00,\"interest_amount\":10.00,\"penalty_amount\":1.00,\"vat_amount\":0.70},{\"principal_amount\":39.99,\"interest_amount\":0.00,\"penalty_amount\":0.00,\"vat_amount\":0.00}]",` +
+			`"penalties":"[{\"penalty_amount\":2.00}]"}}`),
+	}
+	findings, err := CheckConsistency(tx)
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "principal_amount", findings[0].Field)
+		assert.Equal(t, "0.01", findings[0].Delta().String())
+		assert.Equal(t, "C1", findings[0].ChronoSequence.String())
+	}
+
+	fee := Transaction{
+		EventCode: null.NewString("fee"),
+		Message:   null.NewString(`{"fee_amount":200.00,"other_properties":{"fee":"[{\"fee_amount\":100.00}]"}}`),
+	}
+	findings, err = CheckConsistency(fee)
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "100.00", findings[0].Delta().String())
+	}
+
+	others := Transaction{
+		EventCode: null.NewString("others"),
+		Message:   null.NewString(`{"principal_amount":1.00,"interest_amount":0.00,"penalty_amount":0.50,"other_properties":{"advance_payment":"{\"principal_amount\":1.00,\"interest_amount\":0.00,\"penalty_amount\":0.25}","penalties":"[{\"penalty_amount\":0.25}]"}}`),
+	}
+	findings, err = CheckConsistency(others)
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestCheckBodyConsistency_fixture(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	res := CheckBodyConsistency(body, Options{})
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, 258, res.Checked)
+	assert.Empty(t, res.Findings)
+}
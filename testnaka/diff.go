@@ -0,0 +1,333 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Kinds of DiffEntry
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// DiffEntry is a transaction only one extract has, or one amount that
+// differs between the matched transactions of two extracts
+type DiffEntry struct {
+	Kind                 DiffKind
+	AccountNumber        null.Int64
+	AccountSequence      null.Int64
+	EventCode            null.String
+	BeforeChronoSequence null.String
+	AfterChronoSequence  null.String
+	// Amount path such as principal_amount, bills[45].unpaid_vat_amount or
+	// advance_payment.interest_amount. Empty for added and removed transactions.
+	Field  string
+	Before null.Dec2
+	After  null.Dec2
+}
+
+// Delta is After minus Before, a null amount counts as zero
+func (e DiffEntry) Delta() decimal.Dec2 {
+	return e.After.Val.Sub(e.Before.Val)
+}
+
+// DiffResult lists the differences between two extracts
+type DiffResult struct {
+	Entries      []DiffEntry
+	BeforeErrors []TransactionError
+	AfterErrors  []TransactionError
+}
+
+// ReportDiff compares the responses read from before and after and writes the differences to w
+func ReportDiff(before, after io.Reader, w io.Writer, opts Options) (DiffResult, error) {
+	beforeBody, err := DecodeBody(before)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("before: %w", err)
+	}
+	afterBody, err := DecodeBody(after)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("after: %w", err)
+	}
+	res := Diff(beforeBody, afterBody, opts)
+	return res, WriteTable(w, opts.Output, DiffColumns, res.Entries)
+}
+
+// Diff matches the kept transactions of before and after by account_number,
+// account_sequence and event_code. When that key is missing or not unique
+// they are matched by chrono_sequence, then in order of appearance.
+func Diff(before, after Body, opts Options) DiffResult {
+	var res DiffResult
+	b, bErrs := diffSide(before, opts)
+	a, aErrs := diffSide(after, opts)
+	res.BeforeErrors, res.AfterErrors = bErrs, aErrs
+
+	pairs, removed, added := matchTransactions(b, a)
+	for _, p := range pairs {
+		res.Entries = append(res.Entries, diffAmounts(p[0], p[1])...)
+	}
+	for _, t := range removed {
+		res.Entries = append(res.Entries, t.entry(DiffRemoved))
+	}
+	for _, t := range added {
+		res.Entries = append(res.Entries, t.entry(DiffAdded))
+	}
+	sort.SliceStable(res.Entries, func(i, j int) bool {
+		x, y := res.Entries[i], res.Entries[j]
+		if x.AccountNumber.Val != y.AccountNumber.Val {
+			return x.AccountNumber.Val < y.AccountNumber.Val
+		}
+		if x.AccountSequence.Val != y.AccountSequence.Val {
+			return x.AccountSequence.Val < y.AccountSequence.Val
+		}
+		return x.EventCode.String() < y.EventCode.String()
+	})
+	return res
+}
+
+// diffTx is a transaction with its amounts flattened by path
+type diffTx struct {
+	tx      Transaction
+	amounts map[string]null.Dec2
+	// order of appearance in the extract
+	pos int
+}
+
+type diffKey struct {
+	account, sequence int64
+	eventCode         string
+}
+
+func (t diffTx) key() (diffKey, bool) {
+	tx := t.tx
+	if tx.AccountNumber.Null() || tx.AccountSequence.Null() || tx.EventCode.Null() {
+		return diffKey{}, false
+	}
+	return diffKey{tx.AccountNumber.Val, tx.AccountSequence.Val, tx.EventCode.String()}, true
+}
+
+func (t diffTx) entry(kind DiffKind) DiffEntry {
+	e := DiffEntry{
+		Kind:            kind,
+		AccountNumber:   t.tx.AccountNumber,
+		AccountSequence: t.tx.AccountSequence,
+		EventCode:       t.tx.EventCode,
+	}
+	if kind == DiffAdded {
+		e.AfterChronoSequence = t.tx.ChronoSequence
+	} else {
+		e.BeforeChronoSequence = t.tx.ChronoSequence
+	}
+	return e
+}
+
+func diffSide(body Body, opts Options) ([]diffTx, []TransactionError) {
+	var txs []diffTx
+	errs := eachTransaction(body, opts, func(tx Transaction) error {
+		amounts, err := transactionAmounts(opts.registry(), tx)
+		if err == nil {
+			txs = append(txs, diffTx{tx: tx, amounts: amounts, pos: len(txs)})
+		}
+		return err
+	})
+	return txs, errs
+}
+
+// matchTransactions pairs before and after transactions, see Diff
+func matchTransactions(before, after []diffTx) (pairs [][2]diffTx, removed, added []diffTx) {
+	count := func(txs []diffTx) map[diffKey]int {
+		n := map[diffKey]int{}
+		for _, t := range txs {
+			if k, ok := t.key(); ok {
+				n[k]++
+			}
+		}
+		return n
+	}
+	bCount, aCount := count(before), count(after)
+	aByKey := map[diffKey]diffTx{}
+	for _, t := range after {
+		if k, ok := t.key(); ok && aCount[k] == 1 {
+			aByKey[k] = t
+		}
+	}
+
+	used := map[int]bool{}
+	var restBefore []diffTx
+	for _, t := range before {
+		if k, ok := t.key(); ok && bCount[k] == 1 {
+			if match, ok := aByKey[k]; ok {
+				pairs = append(pairs, [2]diffTx{t, match})
+				used[match.pos] = true
+				continue
+			}
+		}
+		restBefore = append(restBefore, t)
+	}
+
+	// fall back to chrono_sequence
+	aByChrono := map[string]diffTx{}
+	for _, t := range after {
+		if !used[t.pos] && t.tx.ChronoSequence.NotNull() {
+			aByChrono[t.tx.ChronoSequence.String()] = t
+		}
+	}
+	var unmatched []diffTx
+	for _, t := range restBefore {
+		if match, ok := aByChrono[t.tx.ChronoSequence.String()]; ok && !used[match.pos] && t.tx.ChronoSequence.NotNull() {
+			pairs = append(pairs, [2]diffTx{t, match})
+			used[match.pos] = true
+			continue
+		}
+		unmatched = append(unmatched, t)
+	}
+
+	// then pair the remaining duplicates of a key in order
+	for _, t := range unmatched {
+		k, ok := t.key()
+		found := false
+		if ok {
+			for _, u := range after {
+				if uk, uok := u.key(); uok && uk == k && !used[u.pos] {
+					pairs = append(pairs, [2]diffTx{t, u})
+					used[u.pos] = true
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			removed = append(removed, t)
+		}
+	}
+	for _, u := range after {
+		if !used[u.pos] {
+			added = append(added, u)
+		}
+	}
+	return pairs, removed, added
+}
+
+// diffAmounts returns one changed entry per amount path that differs
+func diffAmounts(before, after diffTx) []DiffEntry {
+	paths := map[string]bool{}
+	for p := range before.amounts {
+		paths[p] = true
+	}
+	for p := range after.amounts {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var entries []DiffEntry
+	for _, p := range sorted {
+		b, a := before.amounts[p], after.amounts[p]
+		if b.Null() == a.Null() && b.Val == a.Val {
+			continue
+		}
+		e := after.entry(DiffChanged)
+		e.BeforeChronoSequence = before.tx.ChronoSequence
+		e.AfterChronoSequence = after.tx.ChronoSequence
+		e.Field = p
+		e.Before = b
+		e.After = a
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// TransactionAmounts flattens every amount of tx, including the nested
+// bills, penalties, fee and advance_payment, into a map keyed by path
+func TransactionAmounts(tx Transaction) (map[string]null.Dec2, error) {
+	return transactionAmounts(DefaultRegistry, tx)
+}
+
+func transactionAmounts(reg *Registry, tx Transaction) (map[string]null.Dec2, error) {
+	row, err := reg.ProcessTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	amounts := map[string]null.Dec2{}
+	set := func(path string, d null.Dec2) {
+		if d.NotNull() {
+			amounts[path] = d
+		}
+	}
+	set("principal_amount", row.PrincipalAmount)
+	set("interest_amount", row.InterestAmount)
+	set("penalty_amount", row.PenaltyAmount)
+	set("vat_amount", row.VatAmount)
+	set("fee_amount", row.FeeAmount)
+
+	if row.AdvancePayment != "" {
+		var advance *AdvancePayment
+		if err := json.Unmarshal([]byte(row.AdvancePayment), &advance); err != nil {
+			return nil, fmt.Errorf("other_properties[advance_payment]: %w", err)
+		}
+		if advance != nil {
+			set("advance_payment.principal_amount", advance.PrincipalAmount)
+			set("advance_payment.interest_amount", advance.InterestAmount)
+			set("advance_payment.penalty_amount", advance.PenaltyAmount)
+		}
+	}
+
+	bills, err := reg.ExplodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bills {
+		prefix := fmt.Sprintf("%s[%s].", nestedName(b.Kind), b.BillSequence.String())
+		set(prefix+"principal_amount", b.PrincipalAmount)
+		set(prefix+"interest_amount", b.InterestAmount)
+		set(prefix+"penalty_amount", b.PenaltyAmount)
+		set(prefix+"vat_amount", b.VatAmount)
+		set(prefix+"unpaid_principal_amount", b.UnpaidPrincipalAmount)
+		set(prefix+"unpaid_interest_amount", b.UnpaidInterestAmount)
+		set(prefix+"unpaid_penalty_amount", b.UnpaidPenaltyAmount)
+		set(prefix+"unpaid_vat_amount", b.UnpaidVatAmount)
+		set(prefix+"fee_amount", b.FeeAmount)
+	}
+	return amounts, nil
+}
+
+// nestedName is the other_properties key a BillRow kind comes from
+func nestedName(kind string) string {
+	switch kind {
+	case BillKindBill:
+		return "bills"
+	case BillKindPenalty:
+		return "penalties"
+	}
+	return kind
+}
+
+// DiffColumns are the columns of the diff report
+var DiffColumns = []Column[DiffEntry]{
+	{Name: "Kind", Width: 7, Value: func(e DiffEntry) string { return string(e.Kind) }},
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(e DiffEntry) string { return int64Cell(e.AccountNumber) }},
+	{Name: "AccountSequence", Width: 8, Numeric: true, Value: func(e DiffEntry) string { return int64Cell(e.AccountSequence) }},
+	{Name: "EventCode", Width: 10, Value: func(e DiffEntry) string { return stringCell(e.EventCode) }},
+	{Name: "BeforeChronoSequence", Width: 25, Value: func(e DiffEntry) string { return stringCell(e.BeforeChronoSequence) }},
+	{Name: "AfterChronoSequence", Width: 25, Value: func(e DiffEntry) string { return stringCell(e.AfterChronoSequence) }},
+	{Name: "Field", Width: 36, Value: func(e DiffEntry) string { return e.Field }},
+	{Name: "Before", Width: 15, Numeric: true, Value: func(e DiffEntry) string { return dec2Cell(e.Before) }},
+	{Name: "After", Width: 15, Numeric: true, Value: func(e DiffEntry) string { return dec2Cell(e.After) }},
+	{Name: "Delta", Width: 15, Numeric: true, Value: func(e DiffEntry) string {
+		if e.Kind != DiffChanged {
+			return ""
+		}
+		return e.Delta().String()
+	}},
+}
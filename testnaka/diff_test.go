@@ -0,0 +1,80 @@
+package testnaka
+
+import (
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func diffTransaction(chrono string, account, sequence int64, eventCode, message string) Transaction {
+	return Transaction{
+		ChronoSequence:  null.NewString(chrono),
+		AccountNumber:   null.NewInt64(account),
+		AccountSequence: null.NewInt64(sequence),
+		EventCode:       null.NewString(eventCode),
+		Message:         null.NewString(message),
+	}
+}
+
+func TestDiff(t *testing.T) {
+	bills := func(unpaid string) string {
+		return `{"principal_amount":100.00,"other_properties":{"bills":"[{\"bill_sequence\":45,\"principal_amount\":100.00,\"unpaid_principal_amount\":` + unpaid + `}]"}}`
+	}
+	before := Body{ReqBody: []Transaction{
+		diffTransaction("B1", 1, -107, "due_bills", bills("0.00")),
+		diffTransaction("B2", 1, -108, "due_bills", bills("0.00")),
+		diffTransaction("B3", 2, 1, "fee", `{"fee_amount":100.00}`),
+		// duplicate keys are matched by chrono_sequence
+		diffTransaction("D1", 3, 1, "due_bills", `{"principal_amount":1.00}`),
+		diffTransaction("D2", 3, 1, "due_bills", `{"principal_amount":2.00}`),
+	}}
+	after := Body{ReqBody: []Transaction{
+		diffTransaction("A1", 1, -107, "due_bills", bills("5.50")),
+		diffTransaction("A2", 1, -108, "due_bills", bills("0.00")),
+		diffTransaction("A4", 4, 1, "others", `{"principal_amount":1.00}`),
+		diffTransaction("D2", 3, 1, "due_bills", `{"principal_amount":2.50}`),
+		diffTransaction("D1", 3, 1, "due_bills", `{"principal_amount":1.00}`),
+	}}
+
+	res := Diff(before, after, Options{})
+	assert.Empty(t, res.BeforeErrors)
+	assert.Empty(t, res.AfterErrors)
+	if assert.Len(t, res.Entries, 4) {
+		e := res.Entries[0]
+		assert.Equal(t, DiffChanged, e.Kind)
+		assert.Equal(t, "bills[45].unpaid_principal_amount", e.Field)
+		assert.Equal(t, "B1", e.BeforeChronoSequence.String())
+		assert.Equal(t, "A1", e.AfterChronoSequence.String())
+		assert.Equal(t, "5.50", e.Delta().String())
+
+		assert.Equal(t, DiffRemoved, res.Entries[1].Kind)
+		assert.Equal(t, "B3", res.Entries[1].BeforeChronoSequence.String())
+
+		assert.Equal(t, DiffChanged, res.Entries[2].Kind)
+		assert.Equal(t, "principal_amount", res.Entries[2].Field)
+		assert.Equal(t, "D2", res.Entries[2].BeforeChronoSequence.String())
+		assert.Equal(t, "0.50", res.Entries[2].Delta().String())
+
+		assert.Equal(t, DiffAdded, res.Entries[3].Kind)
+		assert.Equal(t, "A4", res.Entries[3].AfterChronoSequence.String())
+	}
+}
+
+func TestDiff_fixtureAgainstItself(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	res := Diff(body, body, Options{})
+	assert.Empty(t, res.Entries)
+	assert.Empty(t, res.BeforeErrors)
+}
+
+func TestTransactionAmounts(t *testing.T) {
+	amounts, err := TransactionAmounts(diffTransaction("C", 1, 1, "others",
+		`{"principal_amount":7032.09,"other_properties":{"advance_payment":"{\"principal_amount\":7032.09}","penalties":"[{\"bill_sequence\":46,\"penalty_amount\":1.00}]"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "7032.09", amounts["advance_payment.principal_amount"].String())
+	assert.Equal(t, "1.00", amounts["penalties[46].penalty_amount"].String())
+	_, ok := amounts["fee_amount"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,199 @@
+package testnaka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/timezone"
+)
+
+// Members of Body with a field, the rest go to Body.Extra
+var bodyFields = map[string]bool{"rs_body": true}
+
+// UnmarshalJSON decodes tx, keeps the members it has no field for in Extra
+// and remembers the order of every member for MarshalJSON.
+// last_updated_datetime is moved to Asia/Bangkok.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	type plain Transaction
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	members, extra, err := objectMembers(data, func(key string) bool {
+		_, ok := transactionFieldIndex[key]
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	p.members = members
+	if p.LastUpdatedDatetime.NotNull() {
+		p.LastUpdatedDatetime.Set(p.LastUpdatedDatetime.Val.In(timezone.GetTimeZone()))
+	}
+	*tx = Transaction(p)
+	return nil
+}
+
+// MarshalJSON encodes a decoded tx with the members of the input in their
+// order, so it re-encodes to the compacted original. Fields set or Extra
+// members added since follow, fields in response order then Extra by name.
+// A Transaction built in code has every field then Extra by name.
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	type plain Transaction
+	return marshalMembers(plain(tx), tx.members, tx.Extra)
+}
+
+// UnmarshalJSON decodes b and keeps the members other than rs_body in Extra
+func (b *Body) UnmarshalJSON(data []byte) error {
+	type plain Body
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	members, extra, err := objectMembers(data, func(key string) bool { return bodyFields[key] })
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	p.members = members
+	*b = Body(p)
+	return nil
+}
+
+// MarshalJSON encodes b like Transaction.MarshalJSON
+func (b Body) MarshalJSON() ([]byte, error) {
+	type plain Body
+	return marshalMembers(plain(b), b.members, b.Extra)
+}
+
+// EncodeBody writes body as compact JSON without escaping HTML characters,
+// so a decoded response re-encodes to the compacted original
+func EncodeBody(w io.Writer, body Body) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(body); err != nil {
+		return fmt.Errorf("encode response: %w", err)
+	}
+	return nil
+}
+
+// objectMembers returns the member names of the object data in order and
+// the members known rejects, nil when there are none
+func objectMembers(data []byte, known func(string) bool) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	var (
+		names []string
+		extra map[string]json.RawMessage
+	)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		name := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		if known(name) {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[name] = raw
+	}
+	return names, extra, nil
+}
+
+// marshalMembers encodes v, a struct, as the object of members in order.
+// Fields v sets that members lacks follow in field order, null ones are
+// left out, then the Extra members members lacks by name. Without members
+// every field is written, null or not.
+func marshalMembers(v interface{}, members []string, extra map[string]json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	names, fields, err := objectMembers(buf.Bytes(), func(string) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(members))
+	var order []string
+	for _, name := range members {
+		_, isField := fields[name]
+		_, isExtra := extra[name]
+		if (isField || isExtra) && !listed[name] {
+			order = append(order, name)
+			listed[name] = true
+		}
+	}
+	for _, name := range names {
+		if listed[name] || (members != nil && string(fields[name]) == "null") {
+			continue
+		}
+		order = append(order, name)
+		listed[name] = true
+	}
+	var added []string
+	for name := range extra {
+		if !listed[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	order = append(order, added...)
+
+	out := []byte{'{'}
+	for i, name := range order {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		key, err := marshalString(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key...)
+		out = append(out, ':')
+		if raw, ok := fields[name]; ok {
+			out = append(out, raw...)
+		} else {
+			out = append(out, extra[name]...)
+		}
+	}
+	return append(out, '}'), nil
+}
+
+// marshalString encodes v compactly without escaping HTML characters
+func marshalString(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// writeJSON writes v compactly without escaping HTML characters
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	s, err := marshalString(v)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(s)
+	return nil
+}
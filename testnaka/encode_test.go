@@ -0,0 +1,69 @@
+package testnaka
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/TN-INCORPORATION/kit/v2/timezone"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeBody_roundTrip(t *testing.T) {
+	raw, err := os.ReadFile(ResponseFile)
+	assert.NoError(t, err)
+	var want bytes.Buffer
+	assert.NoError(t, json.Compact(&want, raw))
+
+	body, err := DecodeBody(bytes.NewReader(raw))
+	assert.NoError(t, err)
+	var got bytes.Buffer
+	assert.NoError(t, EncodeBody(&got, body))
+	assert.Equal(t, want.String()+"\n", got.String())
+}
+
+func TestTransaction_fields(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	tx := body.ReqBody[0]
+	assert.Equal(t, "2025-01-15", tx.TransactionDate.String())
+	assert.Equal(t, int64(1), tx.Thread.Val)
+	assert.Equal(t, timezone.GetTimeZone(), tx.LastUpdatedDatetime.Val.Location())
+	assert.Equal(t, "2025-01-15T11:02:09.023391765+07:00", tx.LastUpdatedDatetime.String())
+	assert.True(t, tx.LastUpdatedJobID.NotNull())
+	assert.True(t, tx.LastUpdatedMessageID.NotNull())
+	assert.Equal(t, "{}", tx.LastUpdatedOtherInfo.String())
+	assert.Nil(t, tx.Extra)
+}
+
+func TestTransaction_extra(t *testing.T) {
+	in := `{"rs_header":{"status":"ok"},"rs_body":[{"transaction_date":"2025-01-15","chrono_sequence":"C1","zz":[1,2],"aa":"<b>"}]}`
+	var body Body
+	assert.NoError(t, json.Unmarshal([]byte(in), &body))
+	assert.Equal(t, `{"status":"ok"}`, string(body.Extra["rs_header"]))
+	assert.Equal(t, `"<b>"`, string(body.ReqBody[0].Extra["aa"]))
+
+	// the members of the input come back as they were, in order
+	var out bytes.Buffer
+	assert.NoError(t, EncodeBody(&out, body))
+	assert.Equal(t, in+"\n", out.String())
+
+	// fields set and members added since follow them
+	body.ReqBody[0].JobID = null.NewString("J1")
+	body.ReqBody[0].Extra["bb"] = json.RawMessage(`true`)
+	out.Reset()
+	assert.NoError(t, EncodeBody(&out, body))
+	assert.Equal(t, `{"rs_header":{"status":"ok"},"rs_body":[{"transaction_date":"2025-01-15","chrono_sequence":"C1","zz":[1,2],"aa":"<b>","job_id":"J1","bb":true}]}`+"\n", out.String())
+}
+
+func TestTransaction_marshalBuilt(t *testing.T) {
+	// built in code, every field then Extra by name
+	tx := Transaction{ChronoSequence: null.NewString("C1"), Extra: map[string]json.RawMessage{"zz": json.RawMessage(`1`), "aa": json.RawMessage(`2`)}}
+	raw, err := json.Marshal(tx)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), `{"transaction_date":null,"chrono_sequence":"C1","job_id":null,`))
+	assert.True(t, strings.HasSuffix(string(raw), `"last_updated_other_info":null,"aa":2,"zz":1}`))
+}
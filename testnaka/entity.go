@@ -1,23 +1,40 @@
 package testnaka
 
 import (
+	"encoding/json"
+
 	"github.com/TN-INCORPORATION/kit/v2/null"
 )
 
 type Body struct {
 	ReqBody []Transaction `json:"rs_body,omitempty"`
+	// Members other than rs_body, kept for re-encoding
+	Extra map[string]json.RawMessage `json:"-"`
+	// names of the decoded members in input order, see MarshalJSON
+	members []string
 }
 
 // Define the main structure
 type Transaction struct {
-	TransactionDate null.String `json:"transaction_date"`
-	ChronoSequence  null.String `json:"chrono_sequence"`
-	JobID           null.String `json:"job_id"`
-	AccountNumber   null.Int64  `json:"account_number"`
-	AccountSequence null.Int64  `json:"account_sequence"`
-	EventCode       null.String `json:"event_code"`
-	Message         null.String `json:"message"`
+	TransactionDate      null.Date   `json:"transaction_date"`
+	ChronoSequence       null.String `json:"chrono_sequence"`
+	JobID                null.String `json:"job_id"`
+	AccountNumber        null.Int64  `json:"account_number"`
+	AccountSequence      null.Int64  `json:"account_sequence"`
+	EventCode            null.String `json:"event_code"`
+	Message              null.String `json:"message"`
+	Thread               null.Int64  `json:"thread"`
+	LastUpdatedJobID     null.String `json:"last_updated_job_id"`
+	LastUpdatedMessageID null.String `json:"last_updated_message_id"`
+	// In Asia/Bangkok once decoded
+	LastUpdatedDatetime    null.Time   `json:"last_updated_datetime"`
 	LastUpdatedDescription null.String `json:"last_updated_description"`
+	LastUpdatedUserID      null.String `json:"last_updated_user_id"`
+	LastUpdatedOtherInfo   null.String `json:"last_updated_other_info"`
+	// Members without a field above, kept for re-encoding
+	Extra map[string]json.RawMessage `json:"-"`
+	// names of the decoded members in input order, see MarshalJSON
+	members []string
 }
 
 // Define specific structures for each event_code
@@ -35,10 +52,11 @@ type DueBillsMessage struct {
 	OtherProperties map[string]interface{} `json:"other_properties"`
 }
 
-// type DueBillsMessageOtherProperties struct {
-// 	Bills   []Bill    `json:"bills"`
-// 	Penalty []Penalty `json:"penalty"`
-// }
+// Typed other_properties of a due_bills message, see DueBillsMessage.DecodeOtherProperties
+type DueBillsMessageOtherProperties struct {
+	Bills     []Bill    `json:"bills"`
+	Penalties []Penalty `json:"penalties"`
+}
 
 type FeeMessage struct {
 	FeeAmount       null.Dec2              `json:"fee_amount"`
@@ -46,9 +64,10 @@ type FeeMessage struct {
 	OtherProperties map[string]interface{} `json:"other_properties"`
 }
 
-// type FeeMessageOtherProperties struct {
-// 	Fee []Fee `json:"fee"`
-// }
+// Typed other_properties of a fee message, see FeeMessage.DecodeOtherProperties
+type FeeMessageOtherProperties struct {
+	Fee []Fee `json:"fee"`
+}
 
 type OthersMessage struct {
 	AccountNumber   null.Int64             `json:"account_number"`
@@ -64,10 +83,12 @@ type OthersMessage struct {
 	OtherProperties map[string]interface{} `json:"other_properties"`
 }
 
-// type OthersMessageOtherProperties struct {
-// 	Penalties      []Penalty      `json:"penalties"`
-// 	AdvancePayment AdvancePayment `json:"advance_payment"`
-// }
+// Typed other_properties of an others message, see OthersMessage.DecodeOtherProperties
+type OthersMessageOtherProperties struct {
+	Penalties []Penalty `json:"penalties"`
+	// nil when the message has no advance_payment
+	AdvancePayment *AdvancePayment `json:"advance_payment"`
+}
 
 // Struct for "bills"
 type Bill struct {
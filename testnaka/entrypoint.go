@@ -0,0 +1,168 @@
+package testnaka
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+)
+
+// Channels of the entry points seen in last_updated_description
+const (
+	ChannelREST  = "REST"
+	ChannelKafka = "KAFKA"
+)
+
+// EntryPoint is the parsed last_updated_description of the call that wrote a
+// transaction, such as
+//
+//	Entry=REST : POST /dloan-payment/v1/adjustment/repayment/back-date,
+//	Entry=KAFKA : v1/dloan-transaction/transactions/deposit-for-repayment,
+type EntryPoint struct {
+	Channel string
+	// HTTP method, empty for Kafka
+	Method string
+	// REST path or Kafka topic
+	Path string
+}
+
+// ParseEntryPoint parses a last_updated_description
+func ParseEntryPoint(s string) (EntryPoint, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(s), "Entry=")
+	if !ok {
+		return EntryPoint{}, fmt.Errorf("entry point %q: want Entry=<channel> : <path>", s)
+	}
+	channel, target, ok := strings.Cut(rest, ":")
+	if !ok {
+		return EntryPoint{}, fmt.Errorf("entry point %q: want Entry=<channel> : <path>", s)
+	}
+	e := EntryPoint{Channel: strings.TrimSpace(channel)}
+	target = strings.TrimSuffix(strings.TrimSpace(target), ",")
+	if method, path, ok := strings.Cut(target, " "); ok && isMethod(method) {
+		e.Method = method
+		target = path
+	}
+	e.Path = strings.TrimSpace(target)
+	if e.Channel == "" || e.Path == "" {
+		return EntryPoint{}, fmt.Errorf("entry point %q: want Entry=<channel> : <path>", s)
+	}
+	return e, nil
+}
+
+// isMethod reports whether s is an upper case HTTP method such as POST
+func isMethod(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats e the way last_updated_description holds it
+func (e EntryPoint) String() string {
+	target := e.Path
+	if e.Method != "" {
+		target = e.Method + " " + e.Path
+	}
+	return "Entry=" + e.Channel + " : " + target + ","
+}
+
+// HasPathPrefix reports whether the path of e starts with one of prefixes
+func (e EntryPoint) HasPathPrefix(prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(e.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// EntryPointSummary holds the transactions and amounts of one entry point
+type EntryPointSummary struct {
+	EntryPoint
+	Transactions    int
+	PrincipalAmount decimal.Dec2
+	InterestAmount  decimal.Dec2
+	PenaltyAmount   decimal.Dec2
+	VatAmount       decimal.Dec2
+	FeeAmount       decimal.Dec2
+}
+
+// Total is the sum of every amount of the summary
+func (s EntryPointSummary) Total() decimal.Dec2 {
+	return s.PrincipalAmount.Add(s.InterestAmount).Add(s.PenaltyAmount).Add(s.VatAmount).Add(s.FeeAmount)
+}
+
+// EntryPointResult holds the summaries ordered by channel, path and method
+type EntryPointResult struct {
+	Summaries []EntryPointSummary
+	Errors    []TransactionError
+}
+
+// ReportEntryPoints totals the response read from r per entry point and
+// writes the breakdown to w
+func ReportEntryPoints(r io.Reader, w io.Writer, opts Options) (EntryPointResult, error) {
+	reg := opts.registry()
+	groups := map[EntryPoint]*EntryPointSummary{}
+	errs, err := Each(r, opts, func(tx Transaction) error {
+		e, err := ParseEntryPoint(tx.LastUpdatedDescription.String())
+		if err != nil {
+			return err
+		}
+		row, err := reg.ProcessTransaction(tx)
+		if err != nil {
+			return err
+		}
+		s, ok := groups[e]
+		if !ok {
+			s = &EntryPointSummary{EntryPoint: e}
+			groups[e] = s
+		}
+		s.Transactions++
+		s.PrincipalAmount = s.PrincipalAmount.Add(row.PrincipalAmount.Val)
+		s.InterestAmount = s.InterestAmount.Add(row.InterestAmount.Val)
+		s.PenaltyAmount = s.PenaltyAmount.Add(row.PenaltyAmount.Val)
+		s.VatAmount = s.VatAmount.Add(row.VatAmount.Val)
+		s.FeeAmount = s.FeeAmount.Add(row.FeeAmount.Val)
+		return nil
+	})
+	res := EntryPointResult{Errors: errs}
+	for _, s := range groups {
+		res.Summaries = append(res.Summaries, *s)
+	}
+	sort.Slice(res.Summaries, func(i, j int) bool {
+		a, b := res.Summaries[i], res.Summaries[j]
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, EntryPointColumns, res.Summaries)
+}
+
+// EntryPointColumns are the columns of the entry point breakdown
+var EntryPointColumns = []Column[EntryPointSummary]{
+	{Name: "Channel", Width: 6, Value: func(s EntryPointSummary) string { return s.Channel }},
+	{Name: "Method", Width: 6, Value: func(s EntryPointSummary) string { return s.Method }},
+	{Name: "Path", Width: 70, Value: func(s EntryPointSummary) string { return s.Path }},
+	{Name: "Transactions", Width: 6, Numeric: true, Value: func(s EntryPointSummary) string { return strconv.Itoa(s.Transactions) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(s EntryPointSummary) string { return s.PrincipalAmount.String() }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(s EntryPointSummary) string { return s.InterestAmount.String() }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(s EntryPointSummary) string { return s.PenaltyAmount.String() }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(s EntryPointSummary) string { return s.VatAmount.String() }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(s EntryPointSummary) string { return s.FeeAmount.String() }},
+	{Name: "TotalAmount", Width: 15, Numeric: true, Value: func(s EntryPointSummary) string { return s.Total().String() }},
+}
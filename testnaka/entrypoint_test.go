@@ -0,0 +1,70 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntryPoint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want EntryPoint
+	}{
+		{"Entry=REST : POST /dloan-payment/v1/adjustment/repayment/back-date,",
+			EntryPoint{Channel: ChannelREST, Method: "POST", Path: "/dloan-payment/v1/adjustment/repayment/back-date"}},
+		{"Entry=KAFKA : v1/dloan-transaction/transactions/deposit-for-repayment,",
+			EntryPoint{Channel: ChannelKafka, Path: "v1/dloan-transaction/transactions/deposit-for-repayment"}},
+		{BillGenerationEntry,
+			EntryPoint{Channel: ChannelKafka, Path: "v1/dloan-interest/accrued-interest/history/bill-generation"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEntryPoint(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.in, got.String())
+	}
+
+	for _, in := range []string{"", "null", "REST : POST /x,", "Entry=REST", "Entry= : /x,", "Entry=REST : ,"} {
+		_, err := ParseEntryPoint(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestReportEntryPoints(t *testing.T) {
+	var out bytes.Buffer
+	res, err := ReportEntryPoints(openFixture(t), &out, Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	var paths []string
+	var n int
+	for _, s := range res.Summaries {
+		paths = append(paths, s.Channel+" "+s.Path)
+		n += s.Transactions
+	}
+	assert.Equal(t, []string{
+		"KAFKA v1/dloan-interest/accrued-interest/history/bill-generation",
+		"KAFKA v1/dloan-transaction/transactions/deposit-for-repayment",
+		"REST /dloan-payment/v1/accounts/clear-flat-rate-pending",
+		"REST /dloan-payment/v1/adjustment/repayment/back-date",
+	}, paths)
+	assert.Equal(t, 258, n)
+	assert.Equal(t, 209, res.Summaries[3].Transactions)
+	assert.Equal(t, 5, strings.Count(out.String(), "\n"))
+}
+
+func TestExtract_channelFilter(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Filter.Channels = []string{ChannelKafka}
+	res, err := Extract(openFixture(t), opts)
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 23)
+
+	opts = DefaultOptions()
+	opts.Filter.PathPrefixes = []string{"/dloan-payment/v1/accounts/"}
+	res, err = Extract(openFixture(t), opts)
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 20)
+}
@@ -0,0 +1,214 @@
+package testnaka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Entry description of the interest bill generation consumer, skipped by default
+const BillGenerationEntry = "Entry=KAFKA : v1/dloan-interest/accrued-interest/history/bill-generation,"
+
+// ErrUnknownEventCode is reported for transactions with an event code we cannot decode
+var ErrUnknownEventCode = errors.New("unknown event code")
+
+// Options controls which transactions are extracted
+type Options struct {
+	// Rules include and exclude transactions by any field
+	Rules Rules
+	// Filter keeps only matching transactions
+	Filter Filter
+	// Output is the format Report writes, pipe-delimited by default
+	Output WriteOptions
+	// Workers decode messages in parallel, output keeps rs_body order.
+	// Zero or one decodes on a single worker.
+	Workers int
+	// Handlers decode messages by event code, DefaultRegistry when nil
+	Handlers *Registry
+	// Masker replaces the identifiers of kept transactions before any
+	// report sees them, nil leaves them. Rules and Filter see the originals.
+	Masker *Masker
+}
+
+// DefaultOptions returns the options Main2 has always used
+func DefaultOptions() Options {
+	return Options{Rules: DefaultRules()}
+}
+
+// Row is one line of the payment report
+type Row struct {
+	AccountNumber   null.Int64
+	AccountSequence null.Int64
+	ChronoSequence  null.String
+	EventCode       null.String
+	PrincipalAmount null.Dec2
+	InterestAmount  null.Dec2
+	PenaltyAmount   null.Dec2
+	VatAmount       null.Dec2
+	FeeAmount       null.Dec2
+	// Raw JSON strings taken from other_properties
+	Bills          string
+	Penalty        string
+	AdvancePayment string
+	Fee            string
+}
+
+// TransactionError describes a transaction that could not be turned into a row
+type TransactionError struct {
+	Index          int
+	ChronoSequence string
+	EventCode      string
+	Err            error
+}
+
+func (e TransactionError) Error() string {
+	return fmt.Sprintf("rs_body[%d] chrono_sequence=%s event_code=%s: %v", e.Index, e.ChronoSequence, e.EventCode, e.Err)
+}
+
+func (e TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// Result holds the extracted rows and the transactions that failed
+type Result struct {
+	Rows   []Row
+	Errors []TransactionError
+}
+
+// Extract reads a publishMessageDetail response and returns its report rows.
+// Only a response that is not valid JSON returns an error, bad transactions
+// are collected in Result.Errors.
+func Extract(r io.Reader, opts Options) (Result, error) {
+	var res Result
+	errs, err := EachOrdered(context.Background(), r, opts, opts.registry().ProcessTransaction, func(row Row) error {
+		res.Rows = append(res.Rows, row)
+		return nil
+	})
+	res.Errors = errs
+	return res, err
+}
+
+// DecodeBody reads a whole publishMessageDetail response, prefer Each or
+// Decoder for large responses
+func DecodeBody(r io.Reader) (Body, error) {
+	var body Body
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return body, fmt.Errorf("decode response: %w", err)
+	}
+	return body, nil
+}
+
+// Report streams rows from r to w as they are decoded and returns the
+// transactions that failed
+func Report(r io.Reader, w io.Writer, opts Options) ([]TransactionError, error) {
+	return ReportContext(context.Background(), r, w, opts)
+}
+
+// ReportContext is Report stopping when ctx is done
+func ReportContext(ctx context.Context, r io.Reader, w io.Writer, opts Options) ([]TransactionError, error) {
+	tw, err := NewTableWriter(w, opts.Output, RowColumns)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := EachOrdered(ctx, r, opts, opts.registry().ProcessTransaction, tw.Write)
+	if err != nil {
+		return errs, err
+	}
+	return errs, tw.Flush()
+}
+
+// ProcessBody turns every transaction of body into a row
+func ProcessBody(body Body, opts Options) Result {
+	var res Result
+	res.Errors = eachTransaction(body, opts, func(tx Transaction) error {
+		row, err := opts.registry().ProcessTransaction(tx)
+		if err == nil {
+			res.Rows = append(res.Rows, row)
+		}
+		return err
+	})
+	return res
+}
+
+// eachTransaction calls fn for the transactions kept by opts and collects its errors
+func eachTransaction(body Body, opts Options, fn func(tx Transaction) error) []TransactionError {
+	var errs []TransactionError
+	for i, tx := range body.ReqBody {
+		if !opts.Keep(tx) {
+			continue
+		}
+		if err := opts.maskThen(tx, fn); err != nil {
+			errs = append(errs, TransactionError{
+				Index:          i,
+				ChronoSequence: tx.ChronoSequence.String(),
+				EventCode:      tx.EventCode.String(),
+				Err:            err,
+			})
+		}
+	}
+	return errs
+}
+
+// maskThen calls fn with tx masked by the Masker of the options
+func (o Options) maskThen(tx Transaction, fn func(tx Transaction) error) error {
+	if o.Masker != nil {
+		var err error
+		if tx, err = o.Masker.MaskTransaction(tx); err != nil {
+			return err
+		}
+	}
+	return fn(tx)
+}
+
+// registry returns the handlers of the options
+func (o Options) registry() *Registry {
+	if o.Handlers == nil {
+		return DefaultRegistry
+	}
+	return o.Handlers
+}
+
+// Keep reports whether tx is selected by the options
+func (o Options) Keep(tx Transaction) bool {
+	return o.Rules.Keep(tx) && o.Filter.Match(tx)
+}
+
+// ProcessTransaction decodes the message of tx with the handler of its
+// event code in DefaultRegistry
+func ProcessTransaction(tx Transaction) (Row, error) {
+	return DefaultRegistry.ProcessTransaction(tx)
+}
+
+// otherProperty returns other_properties[key] when it is a string
+func otherProperty(props map[string]interface{}, key string) string {
+	if raw, ok := props[key]; ok {
+		if s, ok := raw.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// RowColumns are the columns of the payment report
+var RowColumns = []Column[Row]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(r Row) string { return int64Cell(r.AccountNumber) }},
+	{Name: "EventCode", Width: 10, Value: func(r Row) string { return stringCell(r.EventCode) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(r Row) string { return dec2Cell(r.PrincipalAmount) }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(r Row) string { return dec2Cell(r.InterestAmount) }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(r Row) string { return dec2Cell(r.PenaltyAmount) }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(r Row) string { return dec2Cell(r.VatAmount) }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(r Row) string { return dec2Cell(r.FeeAmount) }},
+	{Name: "otherProperties[bill]", Width: 200, Value: func(r Row) string { return r.Bills }},
+	{Name: "otherProperties[penalty]", Width: 200, Value: func(r Row) string { return r.Penalty }},
+	{Name: "otherProperties[advance_payment]", Width: 100, Value: func(r Row) string { return r.AdvancePayment }},
+	{Name: "otherProperties[fee]", Width: 200, Value: func(r Row) string { return r.Fee }},
+}
+
+// WriteRows writes rows as the pipe-delimited report, header first
+func WriteRows(w io.Writer, rows []Row) error {
+	return WriteTable(w, WriteOptions{Format: FormatPipe}, RowColumns, rows)
+}
@@ -0,0 +1,91 @@
+package testnaka
+
+import (
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Filter keeps only the transactions matching every non-empty criterion.
+// The zero Filter keeps everything.
+type Filter struct {
+	AccountNumbers []int64
+	EventCodes     []string
+	JobIDs         []string
+	// Inclusive transaction_date range, a null bound is open
+	DateFrom null.Date
+	DateTo   null.Date
+	// Exact last_updated_description values
+	Descriptions []string
+	// Entry point channels of last_updated_description, e.g. REST or KAFKA
+	Channels []string
+	// Entry point path prefixes, e.g. /dloan-payment/v1/adjustment
+	PathPrefixes []string
+}
+
+// Match reports whether tx passes the filter
+func (f Filter) Match(tx Transaction) bool {
+	if len(f.AccountNumbers) > 0 && !containsInt64(f.AccountNumbers, tx.AccountNumber) {
+		return false
+	}
+	if len(f.EventCodes) > 0 && !containsString(f.EventCodes, tx.EventCode) {
+		return false
+	}
+	if len(f.JobIDs) > 0 && !containsString(f.JobIDs, tx.JobID) {
+		return false
+	}
+	if f.DateFrom.NotNull() || f.DateTo.NotNull() {
+		if tx.TransactionDate.Null() {
+			return false
+		}
+		if f.DateFrom.NotNull() && tx.TransactionDate.Val.Before(f.DateFrom.Val) {
+			return false
+		}
+		if f.DateTo.NotNull() && tx.TransactionDate.Val.After(f.DateTo.Val) {
+			return false
+		}
+	}
+	if len(f.Descriptions) > 0 && !containsString(f.Descriptions, tx.LastUpdatedDescription) {
+		return false
+	}
+	if len(f.Channels) > 0 || len(f.PathPrefixes) > 0 {
+		e, err := ParseEntryPoint(tx.LastUpdatedDescription.String())
+		if err != nil {
+			return false
+		}
+		if len(f.Channels) > 0 && !containsFold(f.Channels, e.Channel) {
+			return false
+		}
+		if len(f.PathPrefixes) > 0 && !e.HasPathPrefix(f.PathPrefixes) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt64(list []int64, v null.Int64) bool {
+	for _, i := range list {
+		if v.Equals(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v null.String) bool {
+	for _, s := range list {
+		if v.Equals(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
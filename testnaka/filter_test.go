@@ -0,0 +1,48 @@
+package testnaka
+
+import (
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	tx := Transaction{
+		TransactionDate:        mustDate(t, "2025-01-15"),
+		AccountNumber:          null.NewInt64(190000003836),
+		EventCode:              null.NewString("due_bills"),
+		JobID:                  null.NewString("250115bc5457fdCD909972"),
+		LastUpdatedDescription: null.NewString("Entry=REST : POST /dloan-payment/v1/adjustment/repayment/back-date,"),
+	}
+	jan15, _ := null.NewDates("2025-01-15")
+	jan16, _ := null.NewDates("2025-01-16")
+
+	assert.True(t, Filter{}.Match(tx))
+	assert.True(t, Filter{AccountNumbers: []int64{1, 190000003836}}.Match(tx))
+	assert.False(t, Filter{AccountNumbers: []int64{1}}.Match(tx))
+	assert.True(t, Filter{EventCodes: []string{"fee", "due_bills"}}.Match(tx))
+	assert.False(t, Filter{EventCodes: []string{"fee"}}.Match(tx))
+	assert.True(t, Filter{JobIDs: []string{"250115bc5457fdCD909972"}}.Match(tx))
+	assert.False(t, Filter{JobIDs: []string{"250115bc5457fdCD909973"}}.Match(tx))
+	assert.True(t, Filter{DateFrom: jan15, DateTo: jan15}.Match(tx))
+	assert.False(t, Filter{DateFrom: jan16}.Match(tx))
+	assert.False(t, Filter{Descriptions: []string{BillGenerationEntry}}.Match(tx))
+	assert.True(t, Filter{Channels: []string{"rest"}}.Match(tx))
+	assert.False(t, Filter{Channels: []string{ChannelKafka}}.Match(tx))
+	assert.True(t, Filter{PathPrefixes: []string{"/x", "/dloan-payment/v1/adjustment/"}}.Match(tx))
+	assert.False(t, Filter{Channels: []string{ChannelREST}, PathPrefixes: []string{"/dloan-payment/v1/accounts/"}}.Match(tx))
+}
+
+func TestExtract_filter(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Filter.AccountNumbers = []int64{190000003836}
+	opts.Filter.EventCodes = []string{"due_bills"}
+	res, err := Extract(openFixture(t), opts)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Rows)
+	for _, row := range res.Rows {
+		assert.Equal(t, "190000003836", row.AccountNumber.String())
+		assert.Equal(t, "due_bills", row.EventCode.String())
+	}
+}
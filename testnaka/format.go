@@ -0,0 +1,385 @@
+package testnaka
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// Output formats of a report
+type Format string
+
+const (
+	// FormatPipe is the legacy pipe-delimited report, values are not escaped
+	FormatPipe     Format = "pipe"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatJSONL    Format = "jsonl"
+	FormatFixed    Format = "fixed"
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats lists every supported output format
+var Formats = []Format{FormatPipe, FormatCSV, FormatTSV, FormatJSONL, FormatFixed, FormatMarkdown}
+
+// ParseFormat parses a format name, the empty string is FormatPipe
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatPipe, nil
+	}
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// WriteOptions selects how a report is written
+type WriteOptions struct {
+	Format Format
+	// BOM starts CSV output with a UTF-8 byte order mark so Excel shows Thai text
+	BOM bool
+}
+
+// Column is one field of a report. The columns of a report are declared
+// once and shared by every format.
+type Column[T any] struct {
+	Name string
+	// Width of the column in FormatFixed, longer values are cut
+	Width int
+	// Numeric columns are right aligned and written as JSON numbers
+	Numeric bool
+	Value   func(T) string
+}
+
+// WriteTable writes rows to w as described by cols
+func WriteTable[T any](w io.Writer, opts WriteOptions, cols []Column[T], rows []T) error {
+	tw, err := NewTableWriter(w, opts, cols)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := tw.Write(row); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// TableWriter writes rows one at a time, the header goes out with the first call
+type TableWriter[T any] struct {
+	cols    []Column[T]
+	enc     recordEncoder
+	started bool
+}
+
+// NewTableWriter returns a writer of cols in the format of opts
+func NewTableWriter[T any](w io.Writer, opts WriteOptions, cols []Column[T]) (*TableWriter[T], error) {
+	fields := make([]field, len(cols))
+	for i, c := range cols {
+		fields[i] = field{name: c.Name, width: c.Width, numeric: c.Numeric}
+		// never cut the header
+		if n := utf8.RuneCountInString(c.Name); fields[i].width < n {
+			fields[i].width = n
+		}
+	}
+	enc, err := newRecordEncoder(w, opts, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &TableWriter[T]{cols: cols, enc: enc}, nil
+}
+
+// Write writes one row
+func (t *TableWriter[T]) Write(row T) error {
+	if err := t.start(); err != nil {
+		return err
+	}
+	values := make([]string, len(t.cols))
+	for i, c := range t.cols {
+		values[i] = c.Value(row)
+	}
+	return t.enc.record(values)
+}
+
+// Flush writes the header of an empty table and flushes buffered output
+func (t *TableWriter[T]) Flush() error {
+	if err := t.start(); err != nil {
+		return err
+	}
+	return t.enc.flush()
+}
+
+func (t *TableWriter[T]) start() error {
+	if t.started {
+		return nil
+	}
+	t.started = true
+	return t.enc.header()
+}
+
+type field struct {
+	name    string
+	width   int
+	numeric bool
+}
+
+// recordEncoder is implemented once per format
+type recordEncoder interface {
+	header() error
+	record(values []string) error
+	flush() error
+}
+
+func newRecordEncoder(w io.Writer, opts WriteOptions, fields []field) (recordEncoder, error) {
+	bw := bufio.NewWriter(w)
+	switch opts.Format {
+	case FormatPipe, "":
+		return &delimitedEncoder{w: bw, fields: fields, sep: "|", escape: func(s string) string { return s }}, nil
+	case FormatTSV:
+		return &delimitedEncoder{w: bw, fields: fields, sep: "\t", escape: tsvEscaper.Replace}, nil
+	case FormatCSV:
+		if opts.BOM {
+			if _, err := bw.WriteString("\ufeff"); err != nil {
+				return nil, err
+			}
+		}
+		cw := csv.NewWriter(bw)
+		cw.UseCRLF = true
+		return &csvEncoder{bw: bw, w: cw, fields: fields}, nil
+	case FormatJSONL:
+		return &jsonlEncoder{w: bw, fields: fields}, nil
+	case FormatFixed:
+		return &fixedEncoder{w: bw, fields: fields}, nil
+	case FormatMarkdown:
+		return &markdownEncoder{w: bw, fields: fields}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", opts.Format)
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// delimitedEncoder writes pipe and TSV output
+type delimitedEncoder struct {
+	w      *bufio.Writer
+	fields []field
+	sep    string
+	escape func(string) string
+}
+
+func (e *delimitedEncoder) header() error {
+	names := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		names[i] = f.name
+	}
+	return e.record(names)
+}
+
+func (e *delimitedEncoder) record(values []string) error {
+	for i, v := range values {
+		if i > 0 {
+			e.w.WriteString(e.sep)
+		}
+		e.w.WriteString(e.escape(v))
+	}
+	_, err := e.w.WriteString("\n")
+	return err
+}
+
+func (e *delimitedEncoder) flush() error {
+	return e.w.Flush()
+}
+
+type csvEncoder struct {
+	bw     *bufio.Writer
+	w      *csv.Writer
+	fields []field
+}
+
+func (e *csvEncoder) header() error {
+	names := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		names[i] = f.name
+	}
+	return e.w.Write(names)
+}
+
+func (e *csvEncoder) record(values []string) error {
+	return e.w.Write(values)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	return e.bw.Flush()
+}
+
+// jsonlEncoder writes one JSON object per row, keys keep the column order
+type jsonlEncoder struct {
+	w      *bufio.Writer
+	fields []field
+}
+
+func (e *jsonlEncoder) header() error {
+	return nil
+}
+
+func (e *jsonlEncoder) record(values []string) error {
+	e.w.WriteByte('{')
+	for i, f := range e.fields {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.name)
+		e.w.Write(key)
+		e.w.WriteByte(':')
+		switch {
+		case values[i] == "":
+			e.w.WriteString("null")
+		case f.numeric:
+			e.w.WriteString(values[i])
+		default:
+			v, _ := json.Marshal(values[i])
+			e.w.Write(v)
+		}
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *jsonlEncoder) flush() error {
+	return e.w.Flush()
+}
+
+// fixedEncoder pads every value to the width of its column
+type fixedEncoder struct {
+	w      *bufio.Writer
+	fields []field
+}
+
+func (e *fixedEncoder) header() error {
+	names := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		names[i] = f.name
+	}
+	return e.line(names, false)
+}
+
+func (e *fixedEncoder) record(values []string) error {
+	return e.line(values, true)
+}
+
+func (e *fixedEncoder) line(values []string, align bool) error {
+	for i, f := range e.fields {
+		if i > 0 {
+			e.w.WriteByte(' ')
+		}
+		e.w.WriteString(pad(values[i], f.width, align && f.numeric))
+	}
+	_, err := e.w.WriteString("\n")
+	return err
+}
+
+func (e *fixedEncoder) flush() error {
+	return e.w.Flush()
+}
+
+// pad cuts or pads s to width runes, numbers are padded on the left
+func pad(s string, width int, right bool) string {
+	s = strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(s)
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:width])
+	}
+	fill := strings.Repeat(" ", width-n)
+	if right {
+		return fill + s
+	}
+	return s + fill
+}
+
+type markdownEncoder struct {
+	w      *bufio.Writer
+	fields []field
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func (e *markdownEncoder) header() error {
+	names := make([]string, len(e.fields))
+	aligns := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		names[i] = f.name
+		aligns[i] = "---"
+		if f.numeric {
+			aligns[i] = "---:"
+		}
+	}
+	if err := e.record(names); err != nil {
+		return err
+	}
+	return e.row(aligns)
+}
+
+func (e *markdownEncoder) record(values []string) error {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = markdownEscaper.Replace(v)
+	}
+	return e.row(escaped)
+}
+
+func (e *markdownEncoder) row(cells []string) error {
+	_, err := e.w.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	return err
+}
+
+func (e *markdownEncoder) flush() error {
+	return e.w.Flush()
+}
+
+// Cell helpers print null values as empty cells
+
+func dec2Cell(d null.Dec2) string {
+	if d.Null() {
+		return ""
+	}
+	return d.String()
+}
+
+func int64Cell(i null.Int64) string {
+	if i.Null() {
+		return ""
+	}
+	return i.String()
+}
+
+func stringCell(s null.String) string {
+	if s.Null() {
+		return ""
+	}
+	return s.String()
+}
+
+func dateCell(d null.Date) string {
+	if d.Null() {
+		return ""
+	}
+	return d.String()
+}
+
+func boolCell(b null.Bool) string {
+	if b.Null() {
+		return ""
+	}
+	return b.String()
+}
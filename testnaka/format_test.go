@@ -0,0 +1,59 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type formatRow struct {
+	Name   string
+	Amount string
+}
+
+var formatColumns = []Column[formatRow]{
+	{Name: "Name", Width: 6, Value: func(r formatRow) string { return r.Name }},
+	{Name: "Amount", Width: 8, Numeric: true, Value: func(r formatRow) string { return r.Amount }},
+}
+
+var formatRows = []formatRow{
+	{Name: "สมชาย", Amount: "100.50"},
+	{Name: "a|b,\"c\"\td", Amount: ""},
+}
+
+func writeFormat(t *testing.T, opts WriteOptions) string {
+	t.Helper()
+	var out bytes.Buffer
+	assert.NoError(t, WriteTable(&out, opts, formatColumns, formatRows))
+	return out.String()
+}
+
+func TestWriteTable_formats(t *testing.T) {
+	assert.Equal(t, "Name|Amount\nสมชาย|100.50\na|b,\"c\"\td|\n", writeFormat(t, WriteOptions{Format: FormatPipe}))
+	assert.Equal(t, "Name\tAmount\nสมชาย\t100.50\na|b,\"c\"\\td\t\n", writeFormat(t, WriteOptions{Format: FormatTSV}))
+	assert.Equal(t, "Name,Amount\r\nสมชาย,100.50\r\n\"a|b,\"\"c\"\"\td\",\r\n", writeFormat(t, WriteOptions{Format: FormatCSV}))
+	assert.Equal(t, "{\"Name\":\"สมชาย\",\"Amount\":100.50}\n{\"Name\":\"a|b,\\\"c\\\"\\td\",\"Amount\":null}\n", writeFormat(t, WriteOptions{Format: FormatJSONL}))
+	assert.Equal(t, "Name   Amount  \nสมชาย    100.50\na|b,\"c         \n", writeFormat(t, WriteOptions{Format: FormatFixed}))
+	assert.Equal(t, "| Name | Amount |\n| --- | ---: |\n| สมชาย | 100.50 |\n| a\\|b,\"c\"\td |  |\n", writeFormat(t, WriteOptions{Format: FormatMarkdown}))
+}
+
+func TestWriteTable_bom(t *testing.T) {
+	out := writeFormat(t, WriteOptions{Format: FormatCSV, BOM: true})
+	assert.True(t, strings.HasPrefix(out, "\ufeffName,Amount"))
+}
+
+func TestWriteTable_emptyHasHeader(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteTable(&out, WriteOptions{Format: FormatCSV}, formatColumns, nil))
+	assert.Equal(t, "Name,Amount\r\n", out.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatPipe, f)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,435 @@
+package testnaka
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/TN-INCORPORATION/kit/v2/timezone"
+)
+
+// Scenario selects the payment Generate writes for an account
+type Scenario string
+
+const (
+	// A counter service repayment read from Kafka
+	ScenarioRepayment Scenario = "repayment"
+	// A repayment posted through the back-date REST call and adjusted back
+	// to original_transaction_date
+	ScenarioBackdate Scenario = "backdate"
+	// A close of the loan with the info_* payoff breakdown
+	ScenarioEarlyPayoff Scenario = "early_payoff"
+	// A repayment cleared by the clear-flat-rate-pending REST call
+	ScenarioClearFlatRatePending Scenario = "clear_flat_rate_pending"
+)
+
+// Scenarios are every scenario Generate knows
+var Scenarios = []Scenario{ScenarioRepayment, ScenarioBackdate, ScenarioEarlyPayoff, ScenarioClearFlatRatePending}
+
+// ParseScenario parses the name of a scenario
+func ParseScenario(s string) (Scenario, error) {
+	for _, sc := range Scenarios {
+		if string(sc) == s {
+			return sc, nil
+		}
+	}
+	names := make([]string, len(Scenarios))
+	for i, sc := range Scenarios {
+		names[i] = string(sc)
+	}
+	return "", fmt.Errorf("unknown scenario %q, want one of %s", s, strings.Join(names, ", "))
+}
+
+// Entry points the generated transactions claim to come from
+const (
+	generatedKafkaTopic = "/dloan-transaction/transactions/deposit-for-repayment"
+	generatedKafkaEntry = "Entry=KAFKA : v1/dloan-transaction/transactions/deposit-for-repayment,"
+	generatedBackdate   = "Entry=REST : POST /dloan-payment/v1/adjustment/repayment/back-date,"
+	generatedClearEntry = "Entry=REST : POST /dloan-payment/v1/accounts/clear-flat-rate-pending,"
+)
+
+// GenerateConfig configures Generate, the zero value writes ten repayments
+// of three bills dated today
+type GenerateConfig struct {
+	// Defaults to 10
+	Accounts int
+	// Bills paid per account, monthly up to the transaction date. Defaults to 3.
+	Bills int
+	// Scenarios are assigned to the accounts in turn, ScenarioRepayment by default
+	Scenarios []Scenario
+	// Defaults to today
+	TransactionDate null.Date
+	// Account numbers count up from it. Defaults to 990000000001, a range
+	// core banking does not issue.
+	FirstAccount int64
+	// The same seed and config give the same body
+	Seed int64
+}
+
+// Generate returns a synthetic publishMessageDetail response. Every
+// message nests its other_properties arrays as JSON strings the way
+// dloan-payment does, its amounts equal the sum of its bills, penalties and
+// fees, and vat is 7% of principal plus interest.
+func Generate(cfg GenerateConfig) (Body, error) {
+	if cfg.Accounts == 0 {
+		cfg.Accounts = 10
+	}
+	if cfg.Bills == 0 {
+		cfg.Bills = 3
+	}
+	if cfg.Accounts < 0 || cfg.Bills < 0 {
+		return Body{}, fmt.Errorf("generate: accounts and bills must not be negative")
+	}
+	if len(cfg.Scenarios) == 0 {
+		cfg.Scenarios = []Scenario{ScenarioRepayment}
+	}
+	for _, sc := range cfg.Scenarios {
+		if _, err := ParseScenario(string(sc)); err != nil {
+			return Body{}, fmt.Errorf("generate: %w", err)
+		}
+	}
+	if cfg.TransactionDate.Null() {
+		cfg.TransactionDate = null.NewDate(date.NowDate())
+	}
+	if cfg.FirstAccount == 0 {
+		cfg.FirstAccount = 990000000001
+	}
+
+	day := cfg.TransactionDate.Val
+	g := &generator{
+		cfg:   cfg,
+		rnd:   rand.New(rand.NewSource(cfg.Seed)),
+		day:   day,
+		clock: time.Date(day.Year(), day.Month(), day.Day(), 11, 2, 0, 0, timezone.GetTimeZone()),
+	}
+	for i := 0; i < cfg.Accounts; i++ {
+		if err := g.account(cfg.FirstAccount+int64(i), cfg.Scenarios[i%len(cfg.Scenarios)]); err != nil {
+			return Body{}, err
+		}
+	}
+	return g.body, nil
+}
+
+// generator holds the random source and the clock of a Generate run
+type generator struct {
+	cfg   GenerateConfig
+	rnd   *rand.Rand
+	day   date.Date
+	clock time.Time
+	body  Body
+}
+
+// generatedBill is a bill with the penalty and fee it accrued while overdue
+type generatedBill struct {
+	Bill
+	Penalty decimal.Dec2
+	Fee     decimal.Dec2
+}
+
+// payment is what the transactions of one account share
+type payment struct {
+	account     int64
+	job         JobID
+	thread      int64
+	node        string
+	description string
+	messageID   string
+	updatedJob  string
+	props       map[string]interface{}
+}
+
+func (g *generator) account(account int64, sc Scenario) error {
+	bills := g.bills()
+	p := g.payment(account, sc)
+
+	var billList []Bill
+	var penalties []Penalty
+	var fees []Fee
+	var principal, interest, vat, penalty, fee decimal.Dec2
+	for _, b := range bills {
+		billList = append(billList, b.Bill)
+		principal = principal.Add(b.PrincipalAmount.Val)
+		interest = interest.Add(b.InterestAmount.Val)
+		vat = vat.Add(b.VatAmount.Val)
+		if !b.Penalty.IsZero() {
+			penalties = append(penalties, Penalty{BillSequence: b.BillSequence, BillDueDate: b.BillDueDate, PenaltyAmount: null.NewDec2(b.Penalty)})
+			penalty = penalty.Add(b.Penalty)
+		}
+		if !b.Fee.IsZero() {
+			fees = append(fees, Fee{LoanDueDate: b.BillDueDate, BillSequence: b.BillSequence, FeeAmount: null.NewDec2(b.Fee)})
+			fee = fee.Add(b.Fee)
+		}
+	}
+	if sc == ScenarioEarlyPayoff {
+		addPayoffInfo(p.props, principal, interest, penalty, vat, fee)
+	}
+
+	// bills go with due_bills, penalties with others and fees with fee
+	var postings []posting
+	props := copyProps(p.props)
+	setProps(props, map[string]interface{}{
+		"oldest_bill_due_date": "9999-12-31",
+		"oldest_stmt_due_date": "9999-12-31",
+	})
+	if err := setNested(props, "bills", billList); err != nil {
+		return err
+	}
+	postings = append(postings, posting{"due_bills", DueBillsMessage{
+		PrincipalAmount: null.NewDec2(principal),
+		InterestAmount:  null.NewDec2(interest),
+		PenaltyAmount:   null.NewDec2(decimal.Dec2{}),
+		VatAmount:       null.NewDec2(vat),
+		OtherProperties: props,
+	}})
+	if len(penalties) > 0 {
+		props := copyProps(p.props)
+		if err := setNested(props, "penalties", penalties); err != nil {
+			return err
+		}
+		postings = append(postings, posting{"others", OthersMessage{
+			PrincipalAmount: null.NewDec2(decimal.Dec2{}),
+			InterestAmount:  null.NewDec2(decimal.Dec2{}),
+			PenaltyAmount:   null.NewDec2(penalty),
+			VatAmount:       null.NewDec2(decimal.Dec2{}),
+			OtherProperties: props,
+		}})
+	}
+	if len(fees) > 0 {
+		props := copyProps(p.props)
+		if err := setNested(props, "fee", fees); err != nil {
+			return err
+		}
+		postings = append(postings, posting{"fee", FeeMessage{
+			FeeAmount:       null.NewDec2(fee),
+			ServiceBranch:   null.NewInt64(0),
+			OtherProperties: props,
+		}})
+	}
+
+	for i, post := range postings {
+		if err := g.transaction(p, int64(i+1), post); err != nil {
+			return err
+		}
+	}
+	if sc == ScenarioBackdate {
+		// the back-date call reverses each posting under a negative
+		// account_sequence of the same job
+		for i, post := range postings {
+			if err := g.transaction(p, int64(-101-i), post); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// posting is a message of an event code before its account fields are set
+type posting struct {
+	eventCode string
+	msg       interface{}
+}
+
+// bills returns the bills paid by one account, the last one due on the
+// transaction date and the earlier ones overdue
+func (g *generator) bills() []generatedBill {
+	first := 1 + g.rnd.Intn(60)
+	bills := make([]generatedBill, g.cfg.Bills)
+	for i := range bills {
+		due := g.day.AddDate(0, i-g.cfg.Bills+1, 0)
+		principal := g.amount(500, 5000)
+		interest := g.amount(10, 3000)
+		b := generatedBill{Bill: Bill{
+			BillSequence:          null.NewInt64(int64(first + i)),
+			BillDueDate:           null.NewString(due.String()),
+			PrincipalAmount:       null.NewDec2(principal),
+			InterestAmount:        null.NewDec2(interest),
+			PenaltyAmount:         null.NewDec2(decimal.Dec2{}),
+			VatAmount:             null.NewDec2(vatOf(principal.Add(interest))),
+			UnpaidPrincipalAmount: null.NewDec2(decimal.Dec2{}),
+			UnpaidInterestAmount:  null.NewDec2(decimal.Dec2{}),
+			UnpaidPenaltyAmount:   null.NewDec2(decimal.Dec2{}),
+			UnpaidVatAmount:       null.NewDec2(decimal.Dec2{}),
+		}}
+		if due.Before(g.day) {
+			b.Penalty = g.amount(1, 30)
+			b.Fee = decimal.NewDec2i(50)
+		}
+		bills[i] = b
+	}
+	return bills
+}
+
+// amount returns a random amount from min to max baht
+func (g *generator) amount(min, max int64) decimal.Dec2 {
+	return decimal.NewDec2Raw(min*100 + g.rnd.Int63n((max-min)*100+1))
+}
+
+// vatOf is 7% of base rounded half up to the satang
+func vatOf(base decimal.Dec2) decimal.Dec2 {
+	return decimal.NewDec2Raw((base.Val*7 + 50) / 100)
+}
+
+func (g *generator) jobID(node string) JobID {
+	return JobID{Date: g.day, Random: fmt.Sprintf("%08x", g.rnd.Uint32()), Node: node, Counter: g.rnd.Intn(1000000)}
+}
+
+// tick advances the clock by up to two milliseconds
+func (g *generator) tick() time.Time {
+	g.clock = g.clock.Add(time.Duration(100000 + g.rnd.Int63n(1900000)))
+	return g.clock
+}
+
+// payment returns the entry point and the other_properties of scenario
+func (g *generator) payment(account int64, sc Scenario) payment {
+	p := payment{account: account, job: g.jobID("CD"), thread: 1 + g.rnd.Int63n(3)}
+	p.updatedJob = p.job.String()
+	dayBefore := g.day.AddDate(0, 0, -1).String()
+	p.props = map[string]interface{}{
+		"is_awaiting_backdate":      "false",
+		"is_clear_pending":          "false",
+		"original_transaction_date": dayBefore,
+		"transaction_type":          "online",
+	}
+	switch sc {
+	case ScenarioRepayment, ScenarioEarlyPayoff:
+		p.node = "344"
+		p.description = generatedKafkaEntry
+		sent := g.clock.Add(-time.Duration(200000000 + g.rnd.Int63n(300000000)))
+		p.messageID = MessageID{Path: generatedKafkaTopic, JobID: p.job, Time: sent}.String()
+		setProps(p.props, map[string]interface{}{
+			"channel":             "",
+			"ref1":                strconv.FormatInt(account, 10),
+			"ref2":                "01",
+			"repayment_by":        "counter-service",
+			"repayment_reference": p.job.String(),
+			"requested_service":   "deposit-for-repay",
+		})
+		if sc == ScenarioEarlyPayoff {
+			p.props["requested_service"] = "deposit-for-close"
+			p.props["is_early_payoff"] = "true"
+		}
+	case ScenarioBackdate, ScenarioClearFlatRatePending:
+		p.node = "254"
+		p.thread = 2
+		p.messageID = g.jobID("CD").String()
+		setProps(p.props, map[string]interface{}{
+			"adjustment_flag":          "false",
+			"is_awaiting_backdate":     "true",
+			"is_payoff":                "false",
+			"original_adjustment_flag": "true",
+			"ref2":                     "1003",
+			"repayment_by":             "kl",
+			"requested_service":        "deposit-for-repay",
+		})
+		if sc == ScenarioBackdate {
+			p.description = generatedBackdate
+			p.props["original_transaction_date"] = g.day.AddDate(0, 0, -1-g.rnd.Intn(30)).String()
+			p.props["ref1"] = fmt.Sprintf("90%08d", g.rnd.Intn(100000000))
+		} else {
+			p.description = generatedClearEntry
+			p.updatedJob = g.jobID("88").String()
+			p.props["is_clear_pending"] = "true"
+			p.props["ref1"] = fmt.Sprintf("30%08d", g.rnd.Intn(100000000))
+			p.props["repayment_reference"] = p.job.String()
+		}
+	}
+	return p
+}
+
+// addPayoffInfo sets the info_* breakdown of an early payoff of the amounts
+func addPayoffInfo(props map[string]interface{}, principal, interest, penalty, vat, fee decimal.Dec2) {
+	net := principal.Add(interest).Add(penalty).Add(vat).Add(fee)
+	// the customer pays whole baht
+	paid := decimal.NewDec2Raw((net.Val + 99) / 100 * 100)
+	setProps(props, map[string]interface{}{
+		"info_amount_to_close":              net.String(),
+		"info_discount_interest_amount":     "0.00",
+		"info_early_interest_payoff_amount": "",
+		"info_fee_payoff_amount":            fee.String(),
+		"info_interest_payoff_amount":       interest.String(),
+		"info_is_early_payoff":              "true",
+		"info_net_payoff_amount":            net.String(),
+		"info_overridden_discount_amount":   "",
+		"info_overridden_interest_amount":   "",
+		"info_overridden_principal_amount":  "",
+		"info_overridden_vat_amount":        "",
+		"info_penalty_payoff_amount":        penalty.String(),
+		"info_principal_payoff_amount":      principal.String(),
+		"info_transaction_amount":           paid.String(),
+		"info_transaction_balance":          principal.String(),
+		"info_transaction_type":             "",
+		"info_unpaid_fee_amount":            "",
+		"info_unpaid_interest_amount":       "",
+		"info_unpaid_penalty_amount":        "",
+		"info_vat_payoff_amount":            vat.String(),
+	})
+}
+
+func copyProps(props map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		out[k] = v
+	}
+	return out
+}
+
+func setProps(props, values map[string]interface{}) {
+	for k, v := range values {
+		props[k] = v
+	}
+}
+
+// setNested stores v in props[key] as a JSON string
+func setNested(props map[string]interface{}, key string, v interface{}) error {
+	s, err := marshalString(v)
+	if err != nil {
+		return fmt.Errorf("generate %s: %w", key, err)
+	}
+	props[key] = s
+	return nil
+}
+
+// transaction appends the transaction of post under account_sequence seq
+func (g *generator) transaction(p payment, seq int64, post posting) error {
+	day := g.day.String()
+	msg := post.msg
+	switch m := msg.(type) {
+	case DueBillsMessage:
+		m.AccountNumber, m.AccountSequence = null.NewInt64(p.account), null.NewInt64(seq)
+		m.EffectiveDate, m.ChannelPostDate = null.NewString(day), null.NewString(day)
+		m.CurrencyCode, m.ServiceBranch = null.NewString("THB"), null.NewInt64(0)
+		msg = m
+	case OthersMessage:
+		m.AccountNumber, m.AccountSequence = null.NewInt64(p.account), null.NewInt64(seq)
+		m.EffectiveDate, m.ChannelPostDate = null.NewString(day), null.NewString(day)
+		m.CurrencyCode, m.ServiceBranch = null.NewString("THB"), null.NewInt64(0)
+		msg = m
+	}
+	message, err := marshalString(msg)
+	if err != nil {
+		return fmt.Errorf("generate %s message: %w", post.eventCode, err)
+	}
+	at := g.tick()
+	g.body.ReqBody = append(g.body.ReqBody, Transaction{
+		TransactionDate:        null.NewDate(g.day),
+		ChronoSequence:         null.NewString(ChronoSequence{Time: at, Node: p.node, Suffix: "A"}.String()),
+		JobID:                  null.NewString(p.job.String()),
+		AccountNumber:          null.NewInt64(p.account),
+		AccountSequence:        null.NewInt64(seq),
+		EventCode:              null.NewString(post.eventCode),
+		Message:                null.NewString(message),
+		Thread:                 null.NewInt64(p.thread),
+		LastUpdatedJobID:       null.NewString(p.updatedJob),
+		LastUpdatedMessageID:   null.NewString(p.messageID),
+		LastUpdatedDatetime:    null.NewTime(at.Add(time.Duration(1000 + g.rnd.Int63n(999000)))),
+		LastUpdatedDescription: null.NewString(p.description),
+		LastUpdatedUserID:      null.NewString("INTERNAL"),
+		LastUpdatedOtherInfo:   null.NewString("{}"),
+	})
+	return nil
+}
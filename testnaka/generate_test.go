@@ -0,0 +1,137 @@
+package testnaka
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generateAll(t *testing.T, seed int64) Body {
+	t.Helper()
+	body, err := Generate(GenerateConfig{
+		Accounts:        8,
+		Bills:           3,
+		Scenarios:       Scenarios,
+		TransactionDate: mustDate(t, "2025-01-15"),
+		Seed:            seed,
+	})
+	assert.NoError(t, err)
+	return body
+}
+
+func TestGenerate_consistent(t *testing.T) {
+	body := generateAll(t, 1)
+	// 3 postings per account, back-date ones reversed
+	assert.Len(t, body.ReqBody, 8*3+2*3)
+
+	res := CheckBodyConsistency(body, Options{})
+	assert.Empty(t, res.Errors)
+	assert.Empty(t, res.Findings)
+	assert.Equal(t, len(body.ReqBody), res.Checked)
+
+	report := ValidateSchema(body, DefaultSchemas)
+	assert.NoError(t, report.Err(StrictAll))
+
+	v := NewIDValidator()
+	for _, tx := range body.ReqBody {
+		v.Check(tx)
+	}
+	assert.Empty(t, v.Report().Issues)
+}
+
+func TestGenerate_amounts(t *testing.T) {
+	body := generateAll(t, 1)
+	for _, tx := range body.ReqBody {
+		if !tx.EventCode.Equals("due_bills") {
+			continue
+		}
+		var msg DueBillsMessage
+		assert.NoError(t, json.Unmarshal([]byte(tx.Message.String()), &msg))
+		props, err := msg.DecodeOtherProperties()
+		assert.NoError(t, err)
+		for _, b := range props.Bills {
+			assert.Equal(t, vatOf(b.PrincipalAmount.Val.Add(b.InterestAmount.Val)), b.VatAmount.Val)
+		}
+	}
+}
+
+func TestGenerate_scenarios(t *testing.T) {
+	body := generateAll(t, 1)
+
+	var payoffs int
+	for _, tx := range body.ReqBody {
+		row, ok, err := PayoffTransaction(tx)
+		assert.NoError(t, err)
+		if ok {
+			payoffs++
+			assert.True(t, row.Balanced(), row.ChronoSequence.String())
+		}
+	}
+	// accounts 3 and 7 pay off with due_bills, others and fee
+	assert.Equal(t, 6, payoffs)
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeBody(&buf, body))
+	payoff, err := ReportPayoff(&buf, io.Discard, Options{})
+	assert.NoError(t, err)
+	if assert.Len(t, payoff.Rows, 2) {
+		assert.Equal(t, 3, payoff.Rows[0].Postings)
+		assert.Equal(t, 3, payoff.Rows[1].Postings)
+	}
+
+	res := AnalyzeBackdates(body, Options{}, mustDate(t, "2025-01-15").Val)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, 0, res.Unpaired())
+	if assert.Len(t, res.Adjustments, 6) {
+		for _, a := range res.Adjustments {
+			assert.True(t, a.Paired())
+			assert.Greater(t, a.ShiftDays().Val, int64(0))
+		}
+	}
+	// back-date and clear-flat-rate-pending accounts await back-date
+	assert.Len(t, res.Awaiting, 4)
+
+	entries := map[string]int{}
+	for _, tx := range body.ReqBody {
+		entries[tx.LastUpdatedDescription.String()]++
+	}
+	assert.Equal(t, map[string]int{
+		generatedKafkaEntry: 12,
+		generatedBackdate:   12,
+		generatedClearEntry: 6,
+	}, entries)
+}
+
+func TestGenerate_nestedEncoding(t *testing.T) {
+	body := generateAll(t, 1)
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeBody(&buf, body))
+	assert.Contains(t, buf.String(), `\"other_properties\":{`)
+	assert.Contains(t, buf.String(), `\"bills\":\"[{\\\"bill_sequence\\\":`)
+
+	decoded, err := DecodeBody(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(body.ReqBody), len(decoded.ReqBody))
+	assert.Equal(t, body.ReqBody[0].Message, decoded.ReqBody[0].Message)
+}
+
+func TestGenerate_seed(t *testing.T) {
+	encode := func(body Body) string {
+		var buf bytes.Buffer
+		assert.NoError(t, EncodeBody(&buf, body))
+		return buf.String()
+	}
+	assert.Equal(t, encode(generateAll(t, 7)), encode(generateAll(t, 7)))
+	assert.NotEqual(t, encode(generateAll(t, 7)), encode(generateAll(t, 8)))
+}
+
+func TestGenerate_invalid(t *testing.T) {
+	_, err := Generate(GenerateConfig{Scenarios: []Scenario{"write_off"}})
+	assert.Error(t, err)
+	_, err = Generate(GenerateConfig{Accounts: -1})
+	assert.Error(t, err)
+	_, err = ParseScenario("early_payoff")
+	assert.NoError(t, err)
+}
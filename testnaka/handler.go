@@ -0,0 +1,353 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// EventHandler decodes the message of one event code. Register a handler to
+// support a new dloan-payment event without touching the reports.
+type EventHandler interface {
+	// Row returns the payment report row of tx
+	Row(tx Transaction) (Row, error)
+	// Bills returns the bill, penalty and fee entries of tx
+	Bills(tx Transaction) ([]BillRow, error)
+	// Check compares the message amounts of tx with its nested entries
+	Check(tx Transaction) ([]Finding, error)
+}
+
+// Registry maps event codes to their handlers, codes without a handler go
+// to the fallback
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]EventHandler
+	fallback EventHandler
+}
+
+// NewRegistry returns a registry of the due_bills, fee and others handlers
+// that reports other codes as ErrUnknownEventCode
+func NewRegistry() *Registry {
+	r := &Registry{handlers: map[string]EventHandler{}, fallback: UnknownEventHandler{}}
+	r.Register("due_bills", dueBillsHandler{})
+	r.Register("fee", feeHandler{})
+	r.Register("others", othersHandler{})
+	return r
+}
+
+// DefaultRegistry is used by Options without Handlers and by the package
+// level ProcessTransaction, ExplodeTransaction and CheckConsistency
+var DefaultRegistry = NewRegistry()
+
+// Register sets the handler of code, replacing any previous one
+func (r *Registry) Register(code string, h EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[code] = h
+}
+
+// SetFallback sets the handler of codes that have none
+func (r *Registry) SetFallback(h EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = h
+}
+
+// Handler returns the handler of code, the fallback when none is registered
+func (r *Registry) Handler(code string) EventHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if h, ok := r.handlers[code]; ok {
+		return h
+	}
+	return r.fallback
+}
+
+// Codes returns the registered event codes in order
+func (r *Registry) Codes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.handlers))
+	for code := range r.handlers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ProcessTransaction decodes the message of tx with the handler of its event code
+func (r *Registry) ProcessTransaction(tx Transaction) (Row, error) {
+	return r.Handler(tx.EventCode.String()).Row(tx)
+}
+
+// ExplodeTransaction returns the bill, penalty and fee entries of tx
+func (r *Registry) ExplodeTransaction(tx Transaction) ([]BillRow, error) {
+	return r.Handler(tx.EventCode.String()).Bills(tx)
+}
+
+// CheckConsistency compares the message amounts of tx with its nested entries
+func (r *Registry) CheckConsistency(tx Transaction) ([]Finding, error) {
+	return r.Handler(tx.EventCode.String()).Check(tx)
+}
+
+// UnknownEventHandler fails every transaction with ErrUnknownEventCode
+type UnknownEventHandler struct{}
+
+func (UnknownEventHandler) Row(tx Transaction) (Row, error) {
+	return newRow(tx), fmt.Errorf("%w: %s", ErrUnknownEventCode, tx.EventCode)
+}
+
+func (UnknownEventHandler) Bills(tx Transaction) ([]BillRow, error) {
+	return nil, fmt.Errorf("%w: %s", ErrUnknownEventCode, tx.EventCode)
+}
+
+func (UnknownEventHandler) Check(tx Transaction) ([]Finding, error) {
+	return nil, fmt.Errorf("%w: %s", ErrUnknownEventCode, tx.EventCode)
+}
+
+// IgnoreEventHandler keeps transactions of unknown codes as rows without
+// amounts, bills or findings
+type IgnoreEventHandler struct{}
+
+func (IgnoreEventHandler) Row(tx Transaction) (Row, error) {
+	return newRow(tx), nil
+}
+
+func (IgnoreEventHandler) Bills(Transaction) ([]BillRow, error) {
+	return nil, nil
+}
+
+func (IgnoreEventHandler) Check(Transaction) ([]Finding, error) {
+	return nil, nil
+}
+
+func newRow(tx Transaction) Row {
+	return Row{
+		AccountNumber:   tx.AccountNumber,
+		AccountSequence: tx.AccountSequence,
+		ChronoSequence:  tx.ChronoSequence,
+		EventCode:       tx.EventCode,
+	}
+}
+
+func newBillRow(tx Transaction) BillRow {
+	return BillRow{
+		AccountNumber:   tx.AccountNumber,
+		AccountSequence: tx.AccountSequence,
+		ChronoSequence:  tx.ChronoSequence,
+		EventCode:       tx.EventCode,
+	}
+}
+
+// checker collects the findings of tx
+type checker struct {
+	base     Finding
+	findings []Finding
+}
+
+func newChecker(tx Transaction) *checker {
+	return &checker{base: Finding{
+		ChronoSequence:  tx.ChronoSequence,
+		AccountNumber:   tx.AccountNumber,
+		AccountSequence: tx.AccountSequence,
+		EventCode:       tx.EventCode,
+	}}
+}
+
+func (c *checker) check(field string, message null.Dec2, nested decimal.Dec2) {
+	if message.Val != nested {
+		f := c.base
+		f.Field = field
+		f.MessageAmount = message.Val
+		f.NestedAmount = nested
+		c.findings = append(c.findings, f)
+	}
+}
+
+// decodeMessage unmarshals the message of tx into msg
+func decodeMessage(tx Transaction, msg interface{}) error {
+	if err := json.Unmarshal([]byte(tx.Message.String()), msg); err != nil {
+		return fmt.Errorf("unmarshal %s message: %w", tx.EventCode, err)
+	}
+	return nil
+}
+
+type dueBillsHandler struct{}
+
+func (dueBillsHandler) Row(tx Transaction) (Row, error) {
+	row := newRow(tx)
+	var msg DueBillsMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return row, err
+	}
+	row.PrincipalAmount = msg.PrincipalAmount
+	row.InterestAmount = msg.InterestAmount
+	row.PenaltyAmount = msg.PenaltyAmount
+	row.VatAmount = msg.VatAmount
+	row.Bills = otherProperty(msg.OtherProperties, "bills")
+	row.Penalty = otherProperty(msg.OtherProperties, "penalties")
+	return row, nil
+}
+
+func (dueBillsHandler) Bills(tx Transaction) ([]BillRow, error) {
+	var msg DueBillsMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	base := newBillRow(tx)
+	var rows []BillRow
+	for _, b := range props.Bills {
+		rows = append(rows, base.withBill(b))
+	}
+	for _, p := range props.Penalties {
+		rows = append(rows, base.withPenalty(p))
+	}
+	return rows, nil
+}
+
+// Check compares principal, interest and vat with bills and penalty with
+// bills plus penalties
+func (dueBillsHandler) Check(tx Transaction) ([]Finding, error) {
+	var msg DueBillsMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	var principal, interest, penalty, vat decimal.Dec2
+	for _, b := range props.Bills {
+		principal = principal.Add(b.PrincipalAmount.Val)
+		interest = interest.Add(b.InterestAmount.Val)
+		penalty = penalty.Add(b.PenaltyAmount.Val)
+		vat = vat.Add(b.VatAmount.Val)
+	}
+	for _, p := range props.Penalties {
+		penalty = penalty.Add(p.PenaltyAmount.Val)
+	}
+	c := newChecker(tx)
+	c.check("principal_amount", msg.PrincipalAmount, principal)
+	c.check("interest_amount", msg.InterestAmount, interest)
+	c.check("penalty_amount", msg.PenaltyAmount, penalty)
+	c.check("vat_amount", msg.VatAmount, vat)
+	return c.findings, nil
+}
+
+type feeHandler struct{}
+
+func (feeHandler) Row(tx Transaction) (Row, error) {
+	row := newRow(tx)
+	var msg FeeMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return row, err
+	}
+	row.FeeAmount = msg.FeeAmount
+	row.Fee = otherProperty(msg.OtherProperties, "fee")
+	return row, nil
+}
+
+func (feeHandler) Bills(tx Transaction) ([]BillRow, error) {
+	var msg FeeMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	base := newBillRow(tx)
+	var rows []BillRow
+	for _, f := range props.Fee {
+		rows = append(rows, base.withFee(f))
+	}
+	return rows, nil
+}
+
+// Check compares fee with the fee entries
+func (feeHandler) Check(tx Transaction) ([]Finding, error) {
+	var msg FeeMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	var fee decimal.Dec2
+	for _, f := range props.Fee {
+		fee = fee.Add(f.FeeAmount.Val)
+	}
+	c := newChecker(tx)
+	c.check("fee_amount", msg.FeeAmount, fee)
+	return c.findings, nil
+}
+
+type othersHandler struct{}
+
+func (othersHandler) Row(tx Transaction) (Row, error) {
+	row := newRow(tx)
+	var msg OthersMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return row, err
+	}
+	row.PrincipalAmount = msg.PrincipalAmount
+	row.InterestAmount = msg.InterestAmount
+	row.PenaltyAmount = msg.PenaltyAmount
+	row.VatAmount = msg.VatAmount
+	row.Penalty = otherProperty(msg.OtherProperties, "penalties")
+	row.AdvancePayment = otherProperty(msg.OtherProperties, "advance_payment")
+	return row, nil
+}
+
+func (othersHandler) Bills(tx Transaction) ([]BillRow, error) {
+	var msg OthersMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	base := newBillRow(tx)
+	var rows []BillRow
+	for _, p := range props.Penalties {
+		rows = append(rows, base.withPenalty(p))
+	}
+	return rows, nil
+}
+
+// Check compares principal and interest with advance_payment and penalty
+// with advance_payment plus penalties
+func (othersHandler) Check(tx Transaction) ([]Finding, error) {
+	var msg OthersMessage
+	if err := decodeMessage(tx, &msg); err != nil {
+		return nil, err
+	}
+	props, err := msg.DecodeOtherProperties()
+	if err != nil {
+		return nil, err
+	}
+	var principal, interest, penalty decimal.Dec2
+	if a := props.AdvancePayment; a != nil {
+		principal = a.PrincipalAmount.Val
+		interest = a.InterestAmount.Val
+		penalty = a.PenaltyAmount.Val
+	}
+	for _, p := range props.Penalties {
+		penalty = penalty.Add(p.PenaltyAmount.Val)
+	}
+	c := newChecker(tx)
+	c.check("principal_amount", msg.PrincipalAmount, principal)
+	c.check("interest_amount", msg.InterestAmount, interest)
+	c.check("penalty_amount", msg.PenaltyAmount, penalty)
+	return c.findings, nil
+}
@@ -0,0 +1,65 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+// payoffHandler is a handler a team could register for a new event code
+type payoffHandler struct{ IgnoreEventHandler }
+
+func (payoffHandler) Row(tx Transaction) (Row, error) {
+	row := newRow(tx)
+	var err error
+	row.PrincipalAmount, err = null.NewDec2s("1.50")
+	return row, err
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	assert.Equal(t, []string{"due_bills", "fee", "others"}, reg.Codes())
+	assert.IsType(t, UnknownEventHandler{}, reg.Handler("payoff"))
+
+	reg.Register("payoff", payoffHandler{})
+	assert.Equal(t, []string{"due_bills", "fee", "others", "payoff"}, reg.Codes())
+
+	tx := Transaction{ChronoSequence: null.NewString("A1"), EventCode: null.NewString("payoff")}
+	row, err := reg.ProcessTransaction(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.50", row.PrincipalAmount.String())
+
+	// DefaultRegistry is untouched
+	_, err = ProcessTransaction(tx)
+	assert.ErrorIs(t, err, ErrUnknownEventCode)
+}
+
+func TestRegistry_fallback(t *testing.T) {
+	in := `{"rs_body":[
+		{"chrono_sequence":"A1","event_code":"write_off","message":"{}"},
+		{"chrono_sequence":"A2","event_code":"fee","message":"{\"fee_amount\":5}"}
+	]}`
+	res, err := Extract(strings.NewReader(in), Options{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Len(t, res.Errors, 1)
+
+	reg := NewRegistry()
+	reg.SetFallback(IgnoreEventHandler{})
+	res, err = Extract(strings.NewReader(in), Options{Handlers: reg})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Rows, 2) {
+		assert.Equal(t, "write_off", res.Rows[0].EventCode.String())
+		assert.True(t, res.Rows[0].PrincipalAmount.Null())
+	}
+
+	bills := ProcessBodyBills(Body{}, Options{Handlers: reg})
+	assert.Empty(t, bills.Errors)
+	cons, err := ReportConsistency(strings.NewReader(in), &bytes.Buffer{}, Options{Handlers: reg})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cons.Checked)
+}
@@ -0,0 +1,387 @@
+package testnaka
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/apptime"
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/timezone"
+)
+
+// Layouts of the timestamps embedded in identifiers, in Asia/Bangkok
+const (
+	idDateLayout = "060102"
+	idTimeLayout = "060102150405"
+)
+
+var (
+	chronoSequencePattern = regexp.MustCompile(`^(\d{12})(\d{9})(\d{3})([A-Z])$`)
+	jobIDPattern          = regexp.MustCompile(`^(\d{6})([0-9a-f]{8})([0-9A-Z]{2})(\d{6})$`)
+	messageIDPattern      = regexp.MustCompile(`^(.*?)(\d{6}[0-9a-f]{8}[0-9A-Z]{2}\d{6})(\d{21})?$`)
+)
+
+// ChronoSequence is a parsed chrono_sequence such as 250115110209021945408254A:
+// yymmddHHMMSS, nine digits of nanoseconds, a three digit node and a suffix
+type ChronoSequence struct {
+	Time   time.Time
+	Node   string
+	Suffix string
+}
+
+// ParseChronoSequence parses s
+func ParseChronoSequence(s string) (ChronoSequence, error) {
+	m := chronoSequencePattern.FindStringSubmatch(s)
+	if m == nil {
+		return ChronoSequence{}, fmt.Errorf("chrono_sequence %q: want yymmddHHMMSS, 9 digit nanoseconds, 3 digit node and a letter", s)
+	}
+	t, err := parseIDTime(m[1], m[2])
+	if err != nil {
+		return ChronoSequence{}, fmt.Errorf("chrono_sequence %q: %w", s, err)
+	}
+	return ChronoSequence{Time: t, Node: m[3], Suffix: m[4]}, nil
+}
+
+func (c ChronoSequence) String() string {
+	return formatIDTime(c.Time) + c.Node + c.Suffix
+}
+
+// Date is the day of the chrono sequence
+func (c ChronoSequence) Date() date.Date {
+	return date.NewDatet(c.Time)
+}
+
+// Compare orders chrono sequences by time, then node and suffix
+func (c ChronoSequence) Compare(o ChronoSequence) int {
+	switch {
+	case c.Time.Before(o.Time):
+		return -1
+	case c.Time.After(o.Time):
+		return 1
+	}
+	return compareStrings(c.Node+c.Suffix, o.Node+o.Suffix)
+}
+
+// JobID is a parsed job_id such as 250115bc5457fdCD909972: yymmdd, eight
+// hex digits, a two character node and a six digit counter
+type JobID struct {
+	Date    date.Date
+	Random  string
+	Node    string
+	Counter int
+}
+
+// ParseJobID parses s
+func ParseJobID(s string) (JobID, error) {
+	m := jobIDPattern.FindStringSubmatch(s)
+	if m == nil {
+		return JobID{}, fmt.Errorf("job_id %q: want yymmdd, 8 hex digits, 2 character node and 6 digit counter", s)
+	}
+	d, err := time.ParseInLocation(idDateLayout, m[1], timezone.GetTimeZone())
+	if err != nil {
+		return JobID{}, fmt.Errorf("job_id %q: %w", s, err)
+	}
+	counter, _ := strconv.Atoi(m[4])
+	return JobID{Date: date.Date{Time: d}, Random: m[2], Node: m[3], Counter: counter}, nil
+}
+
+func (j JobID) String() string {
+	return j.Date.Format(idDateLayout) + j.Random + j.Node + fmt.Sprintf("%06d", j.Counter)
+}
+
+// MessageID is a parsed Kafka message id. It is either a bare job id or the
+// topic path, a job id and the yymmddHHMMSS plus nanoseconds it was sent.
+type MessageID struct {
+	Path  string
+	JobID JobID
+	// Zero for a bare job id
+	Time time.Time
+}
+
+// ParseMessageID parses s
+func ParseMessageID(s string) (MessageID, error) {
+	m := messageIDPattern.FindStringSubmatch(s)
+	if m == nil {
+		return MessageID{}, fmt.Errorf("message id %q: want [path]job_id[timestamp]", s)
+	}
+	job, err := ParseJobID(m[2])
+	if err != nil {
+		return MessageID{}, fmt.Errorf("message id %q: %w", s, err)
+	}
+	id := MessageID{Path: m[1], JobID: job}
+	if m[3] != "" {
+		if id.Time, err = parseIDTime(m[3][:12], m[3][12:]); err != nil {
+			return MessageID{}, fmt.Errorf("message id %q: %w", s, err)
+		}
+	}
+	return id, nil
+}
+
+func (m MessageID) String() string {
+	s := m.Path + m.JobID.String()
+	if !m.Time.IsZero() {
+		s += formatIDTime(m.Time)
+	}
+	return s
+}
+
+func parseIDTime(clock, nanos string) (time.Time, error) {
+	t, err := time.ParseInLocation(idTimeLayout, clock, timezone.GetTimeZone())
+	if err != nil {
+		return t, err
+	}
+	ns, err := strconv.Atoi(nanos)
+	if err != nil {
+		return t, err
+	}
+	return t.Add(time.Duration(ns)), nil
+}
+
+func formatIDTime(t time.Time) string {
+	t = t.In(timezone.GetTimeZone())
+	return t.Format(idTimeLayout) + fmt.Sprintf("%09d", t.Nanosecond())
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// IDGenerator produces identifiers in the formats of core banking for
+// reruns. Chrono sequences it returns are strictly increasing.
+type IDGenerator struct {
+	// ChronoNode is the three digit node of chrono sequences
+	ChronoNode string
+	// JobNode is the two character node of job and message ids
+	JobNode string
+	// Now defaults to apptime.Now
+	Now func() time.Time
+
+	mu      sync.Mutex
+	last    time.Time
+	counter int
+}
+
+// NewIDGenerator returns a generator for the given nodes
+func NewIDGenerator(chronoNode, jobNode string) *IDGenerator {
+	return &IDGenerator{ChronoNode: chronoNode, JobNode: jobNode}
+}
+
+func (g *IDGenerator) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return apptime.Now()
+}
+
+// NextChronoSequence returns a chrono sequence after every one returned before
+func (g *IDGenerator) NextChronoSequence() ChronoSequence {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.now().In(timezone.GetTimeZone())
+	if !t.After(g.last) {
+		t = g.last.Add(time.Nanosecond)
+	}
+	g.last = t
+	return ChronoSequence{Time: t, Node: g.ChronoNode, Suffix: "A"}
+}
+
+// NextJobID returns a job id of today with the next counter
+func (g *IDGenerator) NextJobID() (JobID, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return JobID{}, fmt.Errorf("job_id: %w", err)
+	}
+	g.mu.Lock()
+	g.counter = (g.counter + 1) % 1000000
+	counter := g.counter
+	g.mu.Unlock()
+	return JobID{Date: date.NewDatet(g.now().In(timezone.GetTimeZone())), Random: hex.EncodeToString(b[:]), Node: g.JobNode, Counter: counter}, nil
+}
+
+// NextMessageID returns a message id of a new job, with path and the send
+// time when path is not empty
+func (g *IDGenerator) NextMessageID(path string) (MessageID, error) {
+	job, err := g.NextJobID()
+	if err != nil {
+		return MessageID{}, err
+	}
+	id := MessageID{Path: path, JobID: job}
+	if path != "" {
+		id.Time = g.now()
+	}
+	return id, nil
+}
+
+// IDIssueKind classifies an IDIssue
+type IDIssueKind string
+
+const (
+	// The identifier does not parse
+	IDInvalid IDIssueKind = "invalid"
+	// chrono_sequence was already seen
+	IDDuplicate IDIssueKind = "duplicate"
+	// chrono_sequence is before an earlier one of the same account or job
+	IDOutOfOrder IDIssueKind = "out_of_order"
+	// The chrono_sequence day is not transaction_date
+	IDDateMismatch IDIssueKind = "date_mismatch"
+	// job_id is dated after the chrono_sequence it produced
+	IDJobAfterChrono IDIssueKind = "job_after_chrono"
+)
+
+// IDIssue is an identifier of a transaction that breaks a rule of its format or order
+type IDIssue struct {
+	// rs_body position of the transaction
+	Index          int
+	ChronoSequence string
+	Field          string
+	Kind           IDIssueKind
+	Detail         string
+}
+
+// IDReport holds the issues of an identifier check
+type IDReport struct {
+	Checked int
+	Issues  []IDIssue
+	// Transactions that could not be masked, so were not checked
+	Errors []TransactionError
+}
+
+// Count returns the number of issues of kind
+func (r IDReport) Count(kind IDIssueKind) int {
+	var n int
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// IDValidator checks identifiers transaction by transaction. Within an
+// account and within a job, chrono sequences must increase in rs_body order.
+type IDValidator struct {
+	report IDReport
+	// rs_body position Check assumes for the next transaction
+	next    int
+	seen    map[string]int
+	account map[int64]ChronoSequence
+	job     map[string]ChronoSequence
+}
+
+// NewIDValidator returns an empty validator
+func NewIDValidator() *IDValidator {
+	return &IDValidator{seen: map[string]int{}, account: map[int64]ChronoSequence{}, job: map[string]ChronoSequence{}}
+}
+
+// Check validates the identifiers of the transaction after the last one
+// checked, for callers that check every transaction of rs_body
+func (v *IDValidator) Check(tx Transaction) {
+	v.CheckAt(v.next, tx)
+}
+
+// CheckAt validates the identifiers of tx, found at rs_body[index]
+func (v *IDValidator) CheckAt(index int, tx Transaction) {
+	v.next = index + 1
+	v.report.Checked++
+	cs := tx.ChronoSequence.String()
+	issue := func(field string, kind IDIssueKind, format string, args ...interface{}) {
+		v.report.Issues = append(v.report.Issues, IDIssue{
+			Index:          index,
+			ChronoSequence: cs,
+			Field:          field,
+			Kind:           kind,
+			Detail:         fmt.Sprintf(format, args...),
+		})
+	}
+
+	chrono, err := ParseChronoSequence(cs)
+	if err != nil {
+		issue("chrono_sequence", IDInvalid, "%v", err)
+		return
+	}
+	if first, ok := v.seen[cs]; ok {
+		issue("chrono_sequence", IDDuplicate, "first seen at rs_body[%d]", first)
+	} else {
+		v.seen[cs] = index
+	}
+	if tx.TransactionDate.NotNull() && tx.TransactionDate.Val.String() != chrono.Date().String() {
+		issue("chrono_sequence", IDDateMismatch, "chrono_sequence day %s, transaction_date %s", chrono.Date(), tx.TransactionDate)
+	}
+	if prev, ok := v.account[tx.AccountNumber.Val]; ok && chrono.Compare(prev) < 0 {
+		issue("chrono_sequence", IDOutOfOrder, "before %s of account %d", prev, tx.AccountNumber.Val)
+	}
+	v.account[tx.AccountNumber.Val] = chrono
+
+	if tx.JobID.Null() {
+		return
+	}
+	job, err := ParseJobID(tx.JobID.String())
+	if err != nil {
+		issue("job_id", IDInvalid, "%v", err)
+		return
+	}
+	if job.Date.After(chrono.Date()) {
+		issue("job_id", IDJobAfterChrono, "job_id day %s, chrono_sequence day %s", job.Date, chrono.Date())
+	}
+	if prev, ok := v.job[tx.JobID.String()]; ok && chrono.Compare(prev) < 0 {
+		issue("chrono_sequence", IDOutOfOrder, "before %s of job %s", prev, tx.JobID)
+	}
+	v.job[tx.JobID.String()] = chrono
+}
+
+// Report returns the issues found so far
+func (v *IDValidator) Report() IDReport {
+	return v.report
+}
+
+// ValidateIDs checks the identifiers of every kept transaction read from r
+func ValidateIDs(r io.Reader, opts Options) (IDReport, error) {
+	v := NewIDValidator()
+	var errs []TransactionError
+	d := NewDecoder(r)
+	for {
+		tx, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report := v.Report()
+			report.Errors = errs
+			return report, err
+		}
+		if !opts.Keep(tx) {
+			continue
+		}
+		err = opts.maskThen(tx, func(tx Transaction) error {
+			v.CheckAt(d.Index(), tx)
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, TransactionError{Index: d.Index(), ChronoSequence: tx.ChronoSequence.String(), EventCode: tx.EventCode.String(), Err: err})
+		}
+	}
+	report := v.Report()
+	report.Errors = errs
+	return report, nil
+}
+
+// IDIssueColumns are the columns of the identifier report
+var IDIssueColumns = []Column[IDIssue]{
+	{Name: "Index", Width: 6, Numeric: true, Value: func(i IDIssue) string { return strconv.Itoa(i.Index) }},
+	{Name: "ChronoSequence", Width: 25, Value: func(i IDIssue) string { return i.ChronoSequence }},
+	{Name: "Field", Width: 16, Value: func(i IDIssue) string { return i.Field }},
+	{Name: "Kind", Width: 16, Value: func(i IDIssue) string { return string(i.Kind) }},
+	{Name: "Detail", Width: 80, Value: func(i IDIssue) string { return i.Detail }},
+}
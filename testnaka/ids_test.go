@@ -0,0 +1,147 @@
+package testnaka
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TN-INCORPORATION/kit/v2/timezone"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChronoSequence(t *testing.T) {
+	c, err := ParseChronoSequence("250115110209021945408254A")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 15, 11, 2, 9, 21945408, timezone.GetTimeZone()), c.Time)
+	assert.Equal(t, "254", c.Node)
+	assert.Equal(t, "A", c.Suffix)
+	assert.Equal(t, "2025-01-15", c.Date().String())
+	assert.Equal(t, "250115110209021945408254A", c.String())
+
+	later, _ := ParseChronoSequence("250115110209022546473254A")
+	assert.Equal(t, -1, c.Compare(later))
+	assert.Equal(t, 1, later.Compare(c))
+	assert.Equal(t, 0, c.Compare(c))
+
+	for _, s := range []string{"", "250115110209021945408254", "251315110209021945408254A", "25011511020902194540825AA"} {
+		_, err := ParseChronoSequence(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseJobID(t *testing.T) {
+	j, err := ParseJobID("250115bc5457fdCD909972")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-15", j.Date.String())
+	assert.Equal(t, "bc5457fd", j.Random)
+	assert.Equal(t, "CD", j.Node)
+	assert.Equal(t, 909972, j.Counter)
+	assert.Equal(t, "250115bc5457fdCD909972", j.String())
+
+	j, err = ParseJobID("25011509c459db88002485")
+	assert.NoError(t, err)
+	assert.Equal(t, "88", j.Node)
+	assert.Equal(t, "25011509c459db88002485", j.String())
+
+	_, err = ParseJobID("250115BC5457FDCD909972")
+	assert.Error(t, err)
+}
+
+func TestParseMessageID(t *testing.T) {
+	m, err := ParseMessageID("2501152ac141e8CD035210")
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.Path)
+	assert.True(t, m.Time.IsZero())
+	assert.Equal(t, "2501152ac141e8CD035210", m.String())
+
+	s := "/dloan-transaction/transactions/deposit-for-repayment250115bbd442c0CD260489250115110203456441563"
+	m, err = ParseMessageID(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "/dloan-transaction/transactions/deposit-for-repayment", m.Path)
+	assert.Equal(t, "250115bbd442c0CD260489", m.JobID.String())
+	assert.Equal(t, time.Date(2025, 1, 15, 11, 2, 3, 456441563, timezone.GetTimeZone()), m.Time)
+	assert.Equal(t, s, m.String())
+
+	_, err = ParseMessageID("/dloan-transaction/transactions/deposit-for-repayment")
+	assert.Error(t, err)
+}
+
+func TestIDGenerator(t *testing.T) {
+	now := time.Date(2025, 1, 15, 11, 2, 9, 0, timezone.GetTimeZone())
+	g := NewIDGenerator("254", "CD")
+	g.Now = func() time.Time { return now }
+
+	a := g.NextChronoSequence()
+	b := g.NextChronoSequence()
+	assert.Equal(t, "250115110209000000000254A", a.String())
+	assert.Equal(t, "250115110209000000001254A", b.String())
+	_, err := ParseChronoSequence(b.String())
+	assert.NoError(t, err)
+
+	j1, err := g.NextJobID()
+	assert.NoError(t, err)
+	j2, _ := g.NextJobID()
+	assert.True(t, strings.HasPrefix(j1.String(), "250115"))
+	assert.True(t, strings.HasSuffix(j1.String(), "CD000001"))
+	assert.True(t, strings.HasSuffix(j2.String(), "CD000002"))
+	_, err = ParseJobID(j1.String())
+	assert.NoError(t, err)
+
+	m, err := g.NextMessageID("/dloan-transaction/transactions/deposit-for-repayment")
+	assert.NoError(t, err)
+	parsed, err := ParseMessageID(m.String())
+	assert.NoError(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestValidateIDs(t *testing.T) {
+	report, err := ValidateIDs(openFixture(t), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 258, report.Checked)
+	assert.Empty(t, report.Issues)
+
+	in := `{"rs_body":[
+		{"chrono_sequence":"250115110209021945408254A","job_id":"250115bc5457fdCD909972","account_number":1,"transaction_date":"2025-01-15"},
+		{"chrono_sequence":"250115110209021945408254A","job_id":"250116bc5457fdCD909972","account_number":2,"transaction_date":"2025-01-15"},
+		{"chrono_sequence":"250115100209021945408254A","job_id":"250115bc5457fdCD909972","account_number":1,"transaction_date":"2025-01-14"},
+		{"chrono_sequence":"bad","account_number":3}
+	]}`
+	report, err = ValidateIDs(strings.NewReader(in), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Count(IDDuplicate))
+	assert.Equal(t, 1, report.Count(IDJobAfterChrono))
+	assert.Equal(t, 1, report.Count(IDDateMismatch))
+	assert.Equal(t, 2, report.Count(IDOutOfOrder))
+	assert.Equal(t, 1, report.Count(IDInvalid))
+}
+
+func TestValidateIDs_filteredIndex(t *testing.T) {
+	in := `{"rs_body":[
+		{"chrono_sequence":"250115110209021945408254A","account_number":9,"transaction_date":"2025-01-15"},
+		{"chrono_sequence":"250115110209021945409254A","account_number":1,"transaction_date":"2025-01-15"},
+		{"chrono_sequence":"250115110209021945409254A","account_number":2,"transaction_date":"2025-01-15"}
+	]}`
+	opts := Options{Filter: Filter{AccountNumbers: []int64{1, 2}}}
+	report, err := ValidateIDs(strings.NewReader(in), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	if assert.Len(t, report.Issues, 1) {
+		assert.Equal(t, 2, report.Issues[0].Index)
+		assert.Equal(t, "first seen at rs_body[1]", report.Issues[0].Detail)
+	}
+}
+
+func TestValidateIDs_maskErrors(t *testing.T) {
+	in := `{"rs_body":[
+		{"chrono_sequence":"250115110209021945408254A","account_number":1,"transaction_date":"2025-01-15","message":"{"},
+		{"chrono_sequence":"250115110209021945409254A","account_number":2,"transaction_date":"2025-01-15","message":"{}"}
+	]}`
+	m, _ := NewMasker([]byte("k"))
+	report, err := ValidateIDs(strings.NewReader(in), Options{Masker: m})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Checked)
+	if assert.Len(t, report.Errors, 1) {
+		assert.Equal(t, 0, report.Errors[0].Index)
+		assert.Equal(t, "250115110209021945408254A", report.Errors[0].ChronoSequence)
+	}
+}
@@ -1,12 +1,78 @@
 package testnaka
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/TN-INCORPORATION/kit/v2/null"
 	"github.com/stretchr/testify/assert"
 )
 
+func openFixture(t *testing.T) *os.File {
+	t.Helper()
+	file, err := os.Open(ResponseFile)
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func mustDate(t *testing.T, s string) null.Date {
+	t.Helper()
+	d, err := null.NewDates(s)
+	if err != nil {
+		t.Fatalf("date %q: %v", s, err)
+	}
+	return d
+}
+
 func Test_main2(t *testing.T) {
-	Main2()
-	assert.Equal(t, 1, 2)
+	var out bytes.Buffer
+	errs, err := Report(openFixture(t), &out, DefaultOptions())
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+
+	res, err := Extract(openFixture(t), DefaultOptions())
+	assert.NoError(t, err)
+	// 258 transactions minus 6 bill-generation entries
+	assert.Len(t, res.Rows, 252)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, "AccountNumber|EventCode|PrincipalAmount|InterestAmount|PenaltyAmount|VatAmount|FeeAmount|otherProperties[bill]|otherProperties[penalty]|otherProperties[advance_payment]|otherProperties[fee]", lines[0])
+	assert.Len(t, lines, 253)
+	assert.True(t, strings.HasPrefix(lines[1], "190000003836|due_bills|3766.83|128.50|0.00|272.67||[{"))
+	// penalties used to be read from a "penalty" key that is never sent
+	assert.True(t, strings.HasPrefix(res.Rows[0].Penalty, `[{"bill_sequence":46`))
+}
+
+func TestExtract_noSkip(t *testing.T) {
+	res, err := Extract(openFixture(t), Options{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 258)
+}
+
+func TestExtract_badJSON(t *testing.T) {
+	_, err := Extract(strings.NewReader("{"), DefaultOptions())
+	assert.Error(t, err)
+}
+
+func TestExtract_transactionErrors(t *testing.T) {
+	in := `{"rs_body":[
+		{"chrono_sequence":"A1","account_number":1,"event_code":"due_bills","message":"{\"principal_amount\":1.00}"},
+		{"chrono_sequence":"A2","account_number":2,"event_code":"due_bills","message":"not json"},
+		{"chrono_sequence":"A3","account_number":3,"event_code":"write_off","message":"{}"}
+	]}`
+	res, err := Extract(strings.NewReader(in), DefaultOptions())
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, "1.00", res.Rows[0].PrincipalAmount.String())
+	if assert.Len(t, res.Errors, 2) {
+		assert.Equal(t, 1, res.Errors[0].Index)
+		assert.Equal(t, "A2", res.Errors[0].ChronoSequence)
+		assert.True(t, errors.Is(res.Errors[1], ErrUnknownEventCode))
+	}
 }
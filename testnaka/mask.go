@@ -0,0 +1,351 @@
+package testnaka
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tokenSpace is the number of tokens after the 99 prefix
+const tokenSpace = 10000000000000000
+
+// MaskedKeys are the identifiers Masker replaces, as top-level fields,
+// message keys or keys of the arrays nested in other_properties
+var MaskedKeys = []string{"account_number", "ref1", "ref2"}
+
+// Masker replaces identifiers with tokens. A token is eighteen digits
+// starting with 99, derived from the value with HMAC-SHA256, so the same
+// value gives the same token under the same key wherever it appears and
+// account numbers stay numbers that fit an int64. With 10^16 tokens a
+// collision is unlikely below a hundred million values. It is safe for
+// concurrent use.
+type Masker struct {
+	key []byte
+
+	mu sync.Mutex
+	// original value of every token handed out
+	vault Vault
+}
+
+// NewMasker returns a masker for key, which must not be empty
+func NewMasker(key []byte) (*Masker, error) {
+	if len(key) == 0 {
+		return nil, errors.New("mask: empty key")
+	}
+	return &Masker{key: key, vault: Vault{}}, nil
+}
+
+// Token returns the token of value, empty values stay empty
+func (m *Masker) Token(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(value))
+	token := fmt.Sprintf("99%016d", binary.BigEndian.Uint64(mac.Sum(nil))%tokenSpace)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, ok := m.vault[token]; ok && prev != value {
+		// the values stay out of the message, it may reach a log
+		return "", fmt.Errorf("mask: two values collide on token %s", token)
+	}
+	m.vault[token] = value
+	return token, nil
+}
+
+// Preload adds the tokens of v, usually the saved vault, so Token reports a
+// value colliding with one masked in an earlier run before it is written
+func (m *Masker) Preload(v Vault) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.vault.Merge(v)
+}
+
+// MaskTransaction returns tx with its identifiers replaced by tokens
+func (m *Masker) MaskTransaction(tx Transaction) (Transaction, error) {
+	return rewriteIdentifiers(tx, m.Token)
+}
+
+// MaskBody returns body with the identifiers of every transaction replaced
+func (m *Masker) MaskBody(body Body) (Body, error) {
+	return rewriteBody(body, m.Token)
+}
+
+// Vault returns a copy of the tokens handed out so far
+func (m *Masker) Vault() Vault {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := make(Vault, len(m.vault))
+	for token, value := range m.vault {
+		v[token] = value
+	}
+	return v
+}
+
+// Vault maps tokens back to the values they replaced. Keep it local, it
+// undoes the masking.
+type Vault map[string]string
+
+// Value returns the value token replaced, empty tokens stay empty
+func (v Vault) Value(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	value, ok := v[token]
+	if !ok {
+		return "", fmt.Errorf("unmask: token %q is not in the vault", token)
+	}
+	return value, nil
+}
+
+// UnmaskBody returns body with the tokens of every transaction replaced by
+// the values of the vault
+func (v Vault) UnmaskBody(body Body) (Body, error) {
+	return rewriteBody(body, v.Value)
+}
+
+// Merge adds the tokens of o to v
+func (v Vault) Merge(o Vault) error {
+	for token, value := range o {
+		if prev, ok := v[token]; ok && prev != value {
+			return fmt.Errorf("vault: token %s is %q and %q", token, prev, value)
+		}
+		v[token] = value
+	}
+	return nil
+}
+
+// ReadVault reads a vault written by WriteVault
+func ReadVault(r io.Reader) (Vault, error) {
+	v := Vault{}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+	return v, nil
+}
+
+// WriteVault writes v as a JSON object of token to value
+func WriteVault(w io.Writer, v Vault) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// LoadVault reads the vault file name, an empty vault when it does not exist
+func LoadVault(name string) (Vault, error) {
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return Vault{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadVault(file)
+}
+
+// SaveVault merges v into the vault file name, readable by the owner only
+func SaveVault(name string, v Vault) error {
+	merged, err := LoadVault(name)
+	if err != nil {
+		return err
+	}
+	if err := merged.Merge(v); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := WriteVault(&buf, merged); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0o600)
+}
+
+// ReportMasked streams the kept transactions of r to w as a response of
+// rs_body only, with their identifiers masked by opts.Masker. Transactions
+// that cannot be masked are left out and returned.
+func ReportMasked(r io.Reader, w io.Writer, opts Options) ([]TransactionError, error) {
+	if opts.Masker == nil {
+		return nil, errors.New("mask: no masker")
+	}
+	if _, err := io.WriteString(w, `{"rs_body":[`); err != nil {
+		return nil, err
+	}
+	first := true
+	errs, err := EachOrdered(context.Background(), r, opts, func(tx Transaction) (string, error) {
+		return marshalString(tx)
+	}, func(s string) error {
+		if !first {
+			s = "," + s
+		}
+		first = false
+		_, err := io.WriteString(w, s)
+		return err
+	})
+	if err != nil {
+		return errs, err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return errs, err
+}
+
+func rewriteBody(body Body, fn func(string) (string, error)) (Body, error) {
+	out := body
+	out.ReqBody = make([]Transaction, len(body.ReqBody))
+	for i, tx := range body.ReqBody {
+		var err error
+		if out.ReqBody[i], err = rewriteIdentifiers(tx, fn); err != nil {
+			return body, TransactionError{Index: i, ChronoSequence: tx.ChronoSequence.String(), EventCode: tx.EventCode.String(), Err: err}
+		}
+	}
+	return out, nil
+}
+
+// rewriteIdentifiers replaces the identifiers of tx with fn: the top-level
+// account_number, the MaskedKeys of message at any depth, including inside
+// JSON strings nested in it, and those of unknown members
+func rewriteIdentifiers(tx Transaction, fn func(string) (string, error)) (Transaction, error) {
+	rw := identifierRewriter(fn)
+	if tx.AccountNumber.NotNull() {
+		s, err := fn(strconv.FormatInt(tx.AccountNumber.Val, 10))
+		if err != nil {
+			return tx, err
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return tx, fmt.Errorf("account_number %q is not a number", s)
+		}
+		tx.AccountNumber.Set(n)
+	}
+	if tx.Message.NotNull() && tx.Message.String() != "" {
+		msg, err := rw.rewrite(tx.Message.String())
+		if err != nil {
+			return tx, fmt.Errorf("message: %w", err)
+		}
+		tx.Message.Set(msg)
+	}
+	if len(tx.Extra) > 0 {
+		extra := make(map[string]json.RawMessage, len(tx.Extra))
+		for k, raw := range tx.Extra {
+			var buf bytes.Buffer
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := rw.value(dec, &buf, k); err != nil {
+				return tx, fmt.Errorf("%s: %w", k, err)
+			}
+			extra[k] = buf.Bytes()
+		}
+		tx.Extra = extra
+	}
+	return tx, nil
+}
+
+// identifierRewriter re-encodes JSON in its original key order with the
+// values of MaskedKeys replaced
+type identifierRewriter func(string) (string, error)
+
+// rewrite rewrites the JSON document data
+func (rw identifierRewriter) rewrite(data string) (string, error) {
+	var buf bytes.Buffer
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	if err := rw.value(dec, &buf, ""); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// value copies the next value of dec, the member key of an object, to buf
+func (rw identifierRewriter) value(dec *json.Decoder, buf *bytes.Buffer, key string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		end := json.Delim('}')
+		if t == '[' {
+			end = ']'
+		}
+		buf.WriteRune(rune(t))
+		for first := true; dec.More(); first = false {
+			if !first {
+				buf.WriteByte(',')
+			}
+			member := ""
+			if t == '{' {
+				k, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				member = k.(string)
+				if err := writeJSON(buf, member); err != nil {
+					return err
+				}
+				buf.WriteByte(':')
+			}
+			if err := rw.value(dec, buf, member); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteRune(rune(end))
+	case string:
+		switch {
+		case isMaskedKey(key):
+			if t, err = rw(t); err != nil {
+				return err
+			}
+		case isNestedJSON(t):
+			// arrays and objects other_properties carries as strings
+			if t, err = rw.rewrite(t); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		return writeJSON(buf, t)
+	case json.Number:
+		s := t.String()
+		if isMaskedKey(key) {
+			if s, err = rw(s); err != nil {
+				return err
+			}
+			if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+				return fmt.Errorf("%s %q is not a number", key, s)
+			}
+		}
+		buf.WriteString(s)
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+func isMaskedKey(key string) bool {
+	for _, k := range MaskedKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isNestedJSON reports whether s holds a JSON array or object
+func isNestedJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return (strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{")) && json.Valid([]byte(s))
+}
@@ -0,0 +1,142 @@
+package testnaka
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteIdentifiers_identity(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	same, err := rewriteBody(body, func(s string) (string, error) { return s, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, body, same)
+}
+
+func TestMasker_fixture(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	m, err := NewMasker([]byte("secret"))
+	assert.NoError(t, err)
+	masked, err := m.MaskBody(body)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeBody(&buf, masked))
+	out := buf.String()
+	for _, tx := range body.ReqBody[:20] {
+		assert.NotContains(t, out, tx.AccountNumber.String())
+	}
+	assert.NotContains(t, out, `\"ref1\":\"9030072020\"`)
+
+	// the first transaction, account 190000003836, is masked everywhere
+	token, err := m.Token("190000003836")
+	assert.NoError(t, err)
+	tx := masked.ReqBody[0]
+	assert.Equal(t, token, tx.AccountNumber.String())
+	var msg DueBillsMessage
+	assert.NoError(t, json.Unmarshal([]byte(tx.Message.String()), &msg))
+	assert.Equal(t, token, msg.AccountNumber.String())
+	assert.Equal(t, "128.50", msg.InterestAmount.String())
+	ref1, err := m.Token("9030072020")
+	assert.NoError(t, err)
+	assert.Equal(t, ref1, msg.OtherProperties["ref1"])
+	assert.True(t, strings.HasPrefix(tx.Message.String(), `{"account_number":`+token+`,"account_sequence":-108,`))
+
+	// amounts and consistency are untouched
+	assert.Empty(t, CheckBodyConsistency(masked, Options{}).Findings)
+	assert.Equal(t, len(BuildTimelines(body, DefaultOptions()).Timelines), len(BuildTimelines(masked, DefaultOptions()).Timelines))
+
+	unmasked, err := m.Vault().UnmaskBody(masked)
+	assert.NoError(t, err)
+	assert.Equal(t, body, unmasked)
+}
+
+func TestMasker_consistentTokens(t *testing.T) {
+	a, _ := NewMasker([]byte("k1"))
+	b, _ := NewMasker([]byte("k1"))
+	c, _ := NewMasker([]byte("k2"))
+	ta, _ := a.Token("190000026836")
+	tb, _ := b.Token("190000026836")
+	tc, _ := c.Token("190000026836")
+	assert.Equal(t, ta, tb)
+	assert.NotEqual(t, ta, tc)
+	assert.Len(t, ta, 18)
+	assert.True(t, strings.HasPrefix(ta, "99"))
+
+	empty, err := a.Token("")
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+	_, err = NewMasker(nil)
+	assert.Error(t, err)
+}
+
+func TestMasker_nestedAndExtra(t *testing.T) {
+	in := `{"account_number":1,"message":"{\"other_properties\":{\"bills\":\"[{\\\"ref2\\\":\\\"01\\\",\\\"amount\\\":1.50}]\"}}","partner":{"ref1":"A1"}}`
+	var tx Transaction
+	assert.NoError(t, json.Unmarshal([]byte(in), &tx))
+	m, _ := NewMasker([]byte("k"))
+	masked, err := m.MaskTransaction(tx)
+	assert.NoError(t, err)
+
+	ref2, _ := m.Token("01")
+	ref1, _ := m.Token("A1")
+	assert.Equal(t, `{"other_properties":{"bills":"[{\"ref2\":\"`+ref2+`\",\"amount\":1.50}]"}}`, masked.Message.String())
+	assert.Equal(t, `{"ref1":"`+ref1+`"}`, string(masked.Extra["partner"]))
+
+	_, err = Vault{}.UnmaskBody(Body{ReqBody: []Transaction{masked}})
+	assert.Error(t, err)
+}
+
+func TestSaveVault(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "vault.json")
+	assert.NoError(t, SaveVault(name, Vault{"990000000001": "190000003836"}))
+	assert.NoError(t, SaveVault(name, Vault{"990000000002": "01"}))
+	assert.Error(t, SaveVault(name, Vault{"990000000001": "other"}))
+
+	v, err := LoadVault(name)
+	assert.NoError(t, err)
+	assert.Equal(t, Vault{"990000000001": "190000003836", "990000000002": "01"}, v)
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	missing, err := LoadVault(filepath.Join(t.TempDir(), "none.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestMasker_preload(t *testing.T) {
+	m, _ := NewMasker([]byte("k"))
+	token, _ := m.Token("190000003836")
+
+	// a vault saved by an earlier run holds the token for another value
+	m, _ = NewMasker([]byte("k"))
+	assert.NoError(t, m.Preload(Vault{token: "190000026836"}))
+	_, err := m.Token("190000003836")
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "190000026836")
+}
+
+func TestReportMasked(t *testing.T) {
+	in := `{"rs_body":[{"account_number":1,"event_code":"others","message":"{\"account_number\":1}"},` +
+		`{"account_number":2,"event_code":"others","message":"{\"account_number\":"}]}`
+	m, _ := NewMasker([]byte("k"))
+	var out bytes.Buffer
+	errs, err := ReportMasked(strings.NewReader(in), &out, Options{Masker: m})
+	assert.NoError(t, err)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 1, errs[0].Index)
+	}
+	token, _ := m.Token("1")
+	assert.Equal(t, `{"rs_body":[{"account_number":`+token+`,"event_code":"others","message":"{\"account_number\":`+token+`}"}]}`+"\n", out.String())
+
+	_, err = ReportMasked(strings.NewReader(in), &out, Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The bills, penalties, fee and advance_payment entries of other_properties
+// are JSON documents encoded as strings inside the message. The functions
+// below decode that second stage into the typed structs of entity.go.
+
+// DecodeOtherProperties decodes the bills and penalties of a due_bills message
+func (m DueBillsMessage) DecodeOtherProperties() (DueBillsMessageOtherProperties, error) {
+	var props DueBillsMessageOtherProperties
+	if err := decodeNested(m.OtherProperties, "bills", &props.Bills); err != nil {
+		return props, err
+	}
+	if err := decodeNested(m.OtherProperties, "penalties", &props.Penalties); err != nil {
+		return props, err
+	}
+	return props, nil
+}
+
+// DecodeOtherProperties decodes the fee entries of a fee message
+func (m FeeMessage) DecodeOtherProperties() (FeeMessageOtherProperties, error) {
+	var props FeeMessageOtherProperties
+	if err := decodeNested(m.OtherProperties, "fee", &props.Fee); err != nil {
+		return props, err
+	}
+	return props, nil
+}
+
+// DecodeOtherProperties decodes the penalties and advance payment of an others message
+func (m OthersMessage) DecodeOtherProperties() (OthersMessageOtherProperties, error) {
+	var props OthersMessageOtherProperties
+	if err := decodeNested(m.OtherProperties, "penalties", &props.Penalties); err != nil {
+		return props, err
+	}
+	if err := decodeNested(m.OtherProperties, "advance_payment", &props.AdvancePayment); err != nil {
+		return props, err
+	}
+	return props, nil
+}
+
+// decodeNested unmarshals the JSON string props[key] into v. A missing or
+// empty entry leaves v untouched.
+func decodeNested(props map[string]interface{}, key string, v interface{}) error {
+	raw, ok := props[key]
+	if !ok || raw == nil {
+		return nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("other_properties[%s]: expected JSON string, got %T", key, raw)
+	}
+	if s == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return fmt.Errorf("other_properties[%s]: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDueBillsMessage_DecodeOtherProperties(t *testing.T) {
+	msg := DueBillsMessage{OtherProperties: map[string]interface{}{
+		"bills":     `[{"bill_sequence":45,"bill_due_date":"2024-10-10","principal_amount":3766.83,"interest_amount":128.50,"penalty_amount":0.00,"vat_amount":272.67,"unpaid_principal_amount":0.00,"unpaid_interest_amount":0.00,"unpaid_penalty_amount":0.00,"unpaid_vat_amount":0.00}]`,
+		"penalties": `[{"bill_sequence":46,"bill_due_date":"2024-11-10","penalty_amount":12.34}]`,
+	}}
+	props, err := msg.DecodeOtherProperties()
+	assert.NoError(t, err)
+	if assert.Len(t, props.Bills, 1) {
+		assert.Equal(t, "45", props.Bills[0].BillSequence.String())
+		assert.Equal(t, "3766.83", props.Bills[0].PrincipalAmount.String())
+		assert.Equal(t, "0.00", props.Bills[0].UnpaidVatAmount.String())
+	}
+	if assert.Len(t, props.Penalties, 1) {
+		assert.Equal(t, "12.34", props.Penalties[0].PenaltyAmount.String())
+	}
+}
+
+func TestOthersMessage_DecodeOtherProperties(t *testing.T) {
+	props, err := OthersMessage{OtherProperties: map[string]interface{}{
+		"advance_payment": `{"principal_amount":7032.09,"interest_amount":2091.91,"penalty_amount":0.00}`,
+	}}.DecodeOtherProperties()
+	assert.NoError(t, err)
+	assert.Empty(t, props.Penalties)
+	if assert.NotNil(t, props.AdvancePayment) {
+		assert.Equal(t, "7032.09", props.AdvancePayment.PrincipalAmount.String())
+	}
+
+	props, err = OthersMessage{}.DecodeOtherProperties()
+	assert.NoError(t, err)
+	assert.Nil(t, props.AdvancePayment)
+}
+
+func TestDecodeOtherProperties_errors(t *testing.T) {
+	_, err := FeeMessage{OtherProperties: map[string]interface{}{"fee": 100.0}}.DecodeOtherProperties()
+	assert.Error(t, err)
+	_, err = FeeMessage{OtherProperties: map[string]interface{}{"fee": "[{"}}.DecodeOtherProperties()
+	assert.Error(t, err)
+}
+
+func TestDecodeOtherProperties_fixture(t *testing.T) {
+	var body Body
+	assert.NoError(t, json.NewDecoder(openFixture(t)).Decode(&body))
+	var bills, fees int
+	for _, tx := range body.ReqBody {
+		switch tx.EventCode.String() {
+		case "due_bills":
+			var msg DueBillsMessage
+			assert.NoError(t, json.Unmarshal([]byte(tx.Message.String()), &msg))
+			props, err := msg.DecodeOtherProperties()
+			assert.NoError(t, err)
+			bills += len(props.Bills)
+		case "fee":
+			var msg FeeMessage
+			assert.NoError(t, json.Unmarshal([]byte(tx.Message.String()), &msg))
+			props, err := msg.DecodeOtherProperties()
+			assert.NoError(t, err)
+			fees += len(props.Fee)
+		}
+	}
+	assert.Greater(t, bills, 0)
+	assert.Greater(t, fees, 0)
+}
@@ -0,0 +1,121 @@
+package testnaka
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// EachOrdered streams the transactions of r kept by opts, runs work on up to
+// opts.Workers goroutines and hands the results to emit in rs_body order.
+// Errors returned by work are collected per transaction. An error from emit,
+// a broken response or the cancellation of ctx stops the run. r is not read
+// and work not called once EachOrdered returns.
+func EachOrdered[T any](ctx context.Context, r io.Reader, opts Options, work func(Transaction) (T, error), emit func(T) error) ([]TransactionError, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// the reader and the workers are done before EachOrdered returns
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type result struct {
+		value T
+		err   error
+	}
+	type job struct {
+		index int
+		tx    Transaction
+		out   chan result
+	}
+	jobs := make(chan job)
+	// order bounds the transactions in flight and keeps them in rs_body order
+	order := make(chan job, 2*workers)
+	var readErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(order)
+		defer close(jobs)
+		d := NewDecoder(r)
+		for {
+			tx, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				return
+			}
+			if !opts.Keep(tx) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			j := job{index: d.Index(), tx: tx, out: make(chan result, 1)}
+			select {
+			case order <- j:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var v T
+				err := opts.maskThen(j.tx, func(tx Transaction) error {
+					var err error
+					v, err = work(tx)
+					return err
+				})
+				j.out <- result{value: v, err: err}
+			}
+		}()
+	}
+
+	var errs []TransactionError
+	for j := range order {
+		if err := ctx.Err(); err != nil {
+			return errs, err
+		}
+		var res result
+		select {
+		case res = <-j.out:
+		case <-ctx.Done():
+			return errs, ctx.Err()
+		}
+		if res.err != nil {
+			errs = append(errs, TransactionError{
+				Index:          j.index,
+				ChronoSequence: j.tx.ChronoSequence.String(),
+				EventCode:      j.tx.EventCode.String(),
+				Err:            res.err,
+			})
+			continue
+		}
+		if err := emit(res.value); err != nil {
+			return errs, err
+		}
+	}
+	// order is closed after readErr is set
+	if readErr != nil {
+		return errs, readErr
+	}
+	return errs, ctx.Err()
+}
@@ -0,0 +1,114 @@
+package testnaka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_workersKeepOrder(t *testing.T) {
+	var want bytes.Buffer
+	wantErrs, err := Report(openFixture(t), &want, Options{})
+	assert.NoError(t, err)
+
+	for _, workers := range []int{2, 8, 64} {
+		var got bytes.Buffer
+		errs, err := Report(openFixture(t), &got, Options{Workers: workers})
+		assert.NoError(t, err)
+		assert.Equal(t, want.String(), got.String(), "workers=%d", workers)
+		assert.Equal(t, wantErrs, errs)
+	}
+}
+
+func TestReportBills_workersKeepOrder(t *testing.T) {
+	var want, got bytes.Buffer
+	_, err := ReportBills(openFixture(t), &want, Options{})
+	assert.NoError(t, err)
+	_, err = ReportBills(openFixture(t), &got, Options{Workers: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, want.String(), got.String())
+}
+
+func TestEachOrdered_errorsInOrder(t *testing.T) {
+	in := `{"rs_body":[
+		{"chrono_sequence":"A1","event_code":"due_bills","message":"{}"},
+		{"chrono_sequence":"A2","event_code":"nope","message":"{}"},
+		{"chrono_sequence":"A3","event_code":"fee","message":"not json"},
+		{"chrono_sequence":"A4","event_code":"fee","message":"{}"}
+	]}`
+	var rows []string
+	errs, err := EachOrdered(context.Background(), strings.NewReader(in), Options{Workers: 4}, ProcessTransaction, func(row Row) error {
+		rows = append(rows, row.ChronoSequence.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A1", "A4"}, rows)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, 1, errs[0].Index)
+		assert.ErrorIs(t, errs[0], ErrUnknownEventCode)
+		assert.Equal(t, "A3", errs[1].ChronoSequence)
+	}
+}
+
+func TestEachOrdered_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var emitted int
+	_, err := EachOrdered(ctx, openFixture(t), Options{Workers: 4}, ProcessTransaction, func(Row) error {
+		emitted++
+		if emitted == 3 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, emitted)
+}
+
+func TestEachOrdered_emitError(t *testing.T) {
+	boom := errors.New("disk full")
+	_, err := EachOrdered(context.Background(), openFixture(t), Options{Workers: 4}, ProcessTransaction, func(Row) error {
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+}
+
+// countingReader counts the reads of r
+type countingReader struct {
+	r     io.Reader
+	reads atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads.Add(1)
+	return c.r.Read(p)
+}
+
+func TestEachOrdered_stopsReading(t *testing.T) {
+	before := runtime.NumGoroutine()
+	// small reads keep the decoder busy long after the first row
+	r := &countingReader{r: iotest.OneByteReader(openFixture(t))}
+	var worked atomic.Int64
+	_, err := EachOrdered(context.Background(), r, Options{Workers: 4}, func(tx Transaction) (Row, error) {
+		worked.Add(1)
+		return ProcessTransaction(tx)
+	}, func(Row) error {
+		return errors.New("disk full")
+	})
+	assert.Error(t, err)
+
+	reads, calls := r.reads.Load(), worked.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, reads, r.reads.Load())
+	assert.Equal(t, calls, worked.Load())
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -0,0 +1,293 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// PayoffInfo holds the info_* fields a payoff writes into other_properties.
+// They are strings in the message, an empty string is null.
+type PayoffInfo struct {
+	IsEarlyPayoff   null.Bool
+	TransactionType null.String
+
+	TransactionAmount  null.Dec2
+	TransactionBalance null.Dec2
+	AmountToClose      null.Dec2
+	NetPayoffAmount    null.Dec2
+
+	PrincipalPayoffAmount     null.Dec2
+	InterestPayoffAmount      null.Dec2
+	EarlyInterestPayoffAmount null.Dec2
+	PenaltyPayoffAmount       null.Dec2
+	VatPayoffAmount           null.Dec2
+	FeePayoffAmount           null.Dec2
+	DiscountInterestAmount    null.Dec2
+
+	// Set by an officer, they replace the computed amounts
+	OverriddenPrincipalAmount null.Dec2
+	OverriddenInterestAmount  null.Dec2
+	OverriddenVatAmount       null.Dec2
+	OverriddenDiscountAmount  null.Dec2
+
+	UnpaidInterestAmount null.Dec2
+	UnpaidPenaltyAmount  null.Dec2
+	UnpaidFeeAmount      null.Dec2
+}
+
+// HasPayoffInfo reports whether props holds any info_* field
+func HasPayoffInfo(props map[string]interface{}) bool {
+	for k := range props {
+		if strings.HasPrefix(k, "info_") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePayoffInfo parses the info_* fields of props. info_is_early_payoff
+// falls back to is_early_payoff when it is empty.
+func ParsePayoffInfo(props map[string]interface{}) (PayoffInfo, error) {
+	var p PayoffInfo
+	amounts := map[string]*null.Dec2{
+		"info_transaction_amount":           &p.TransactionAmount,
+		"info_transaction_balance":          &p.TransactionBalance,
+		"info_amount_to_close":              &p.AmountToClose,
+		"info_net_payoff_amount":            &p.NetPayoffAmount,
+		"info_principal_payoff_amount":      &p.PrincipalPayoffAmount,
+		"info_interest_payoff_amount":       &p.InterestPayoffAmount,
+		"info_early_interest_payoff_amount": &p.EarlyInterestPayoffAmount,
+		"info_penalty_payoff_amount":        &p.PenaltyPayoffAmount,
+		"info_vat_payoff_amount":            &p.VatPayoffAmount,
+		"info_fee_payoff_amount":            &p.FeePayoffAmount,
+		"info_discount_interest_amount":     &p.DiscountInterestAmount,
+		"info_overridden_principal_amount":  &p.OverriddenPrincipalAmount,
+		"info_overridden_interest_amount":   &p.OverriddenInterestAmount,
+		"info_overridden_vat_amount":        &p.OverriddenVatAmount,
+		"info_overridden_discount_amount":   &p.OverriddenDiscountAmount,
+		"info_unpaid_interest_amount":       &p.UnpaidInterestAmount,
+		"info_unpaid_penalty_amount":        &p.UnpaidPenaltyAmount,
+		"info_unpaid_fee_amount":            &p.UnpaidFeeAmount,
+	}
+	for _, key := range payoffInfoKeys {
+		dst, ok := amounts[key]
+		if !ok {
+			continue
+		}
+		s, err := infoString(props, key)
+		if err != nil {
+			return p, err
+		}
+		if s == "" {
+			continue
+		}
+		if *dst, err = null.NewDec2s(s); err != nil {
+			return p, fmt.Errorf("other_properties[%s]: %w", key, err)
+		}
+	}
+
+	s, err := infoString(props, "info_transaction_type")
+	if err != nil {
+		return p, err
+	}
+	if s != "" {
+		p.TransactionType = null.NewString(s)
+	}
+
+	for _, key := range []string{"info_is_early_payoff", "is_early_payoff"} {
+		s, err := infoString(props, key)
+		if err != nil {
+			return p, err
+		}
+		if s == "" {
+			continue
+		}
+		if p.IsEarlyPayoff, err = null.NewBools(s); err != nil {
+			return p, fmt.Errorf("other_properties[%s]: %w", key, err)
+		}
+		break
+	}
+	return p, nil
+}
+
+// infoString returns the string value of props[key], "" when missing or null
+func infoString(props map[string]interface{}, key string) (string, error) {
+	switch v := props[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	default:
+		return "", fmt.Errorf("other_properties[%s] is %T, want string", key, v)
+	}
+}
+
+// override returns the overridden amount when one is set
+func override(computed, overridden null.Dec2) decimal.Dec2 {
+	if overridden.NotNull() {
+		return overridden.Val
+	}
+	return computed.Val
+}
+
+// Principal is the principal paid off, the overridden one when set
+func (p PayoffInfo) Principal() decimal.Dec2 {
+	return override(p.PrincipalPayoffAmount, p.OverriddenPrincipalAmount)
+}
+
+// Interest is the interest paid off: the overridden interest when set, else
+// the early payoff interest when set, else the accrued interest
+func (p PayoffInfo) Interest() decimal.Dec2 {
+	interest := p.InterestPayoffAmount
+	if p.EarlyInterestPayoffAmount.NotNull() {
+		interest = p.EarlyInterestPayoffAmount
+	}
+	return override(interest, p.OverriddenInterestAmount)
+}
+
+// Vat is the vat paid off, the overridden one when set
+func (p PayoffInfo) Vat() decimal.Dec2 {
+	return override(p.VatPayoffAmount, p.OverriddenVatAmount)
+}
+
+// Discount is the interest waived, the overridden one when set
+func (p PayoffInfo) Discount() decimal.Dec2 {
+	return override(p.DiscountInterestAmount, p.OverriddenDiscountAmount)
+}
+
+// ExpectedNetPayoff is principal, interest, penalty, vat and fee minus the discount
+func (p PayoffInfo) ExpectedNetPayoff() decimal.Dec2 {
+	return p.Principal().Add(p.Interest()).Add(p.PenaltyPayoffAmount.Val).Add(p.Vat()).
+		Add(p.FeePayoffAmount.Val).Sub(p.Discount())
+}
+
+// Delta is the net payoff amount minus the expected one
+func (p PayoffInfo) Delta() decimal.Dec2 {
+	return p.NetPayoffAmount.Val.Sub(p.ExpectedNetPayoff())
+}
+
+// Balanced reports whether the net payoff equals the sum of its parts minus discounts
+func (p PayoffInfo) Balanced() bool {
+	return p.Delta().IsZero()
+}
+
+// PayoffRow is one early payoff of the payoff report. Every posting of the
+// payoff, the transactions of its account and job, carries the same info_*
+// fields.
+type PayoffRow struct {
+	AccountNumber null.Int64
+	JobID         null.String
+	// Earliest chrono sequence of the postings
+	ChronoSequence null.String
+	Postings       int
+	PayoffInfo
+}
+
+// PayoffResult holds one row per early payoff in the rs_body order of their
+// first posting, and the transactions that failed
+type PayoffResult struct {
+	Rows   []PayoffRow
+	Errors []TransactionError
+}
+
+// Unbalanced returns the rows whose net payoff is not the sum of its parts
+func (res PayoffResult) Unbalanced() []PayoffRow {
+	var rows []PayoffRow
+	for _, r := range res.Rows {
+		if !r.Balanced() {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// PayoffTransaction returns the payoff info of tx as a payoff of one
+// posting, ok is false when tx is not an early payoff
+func PayoffTransaction(tx Transaction) (row PayoffRow, ok bool, err error) {
+	var msg struct {
+		OtherProperties map[string]interface{} `json:"other_properties"`
+	}
+	if err := json.Unmarshal([]byte(tx.Message.String()), &msg); err != nil {
+		return row, false, fmt.Errorf("unmarshal %s message: %w", tx.EventCode, err)
+	}
+	if !HasPayoffInfo(msg.OtherProperties) {
+		return row, false, nil
+	}
+	info, err := ParsePayoffInfo(msg.OtherProperties)
+	if err != nil {
+		return row, false, err
+	}
+	row = PayoffRow{
+		AccountNumber:  tx.AccountNumber,
+		JobID:          tx.JobID,
+		ChronoSequence: tx.ChronoSequence,
+		Postings:       1,
+		PayoffInfo:     info,
+	}
+	return row, info.IsEarlyPayoff.Equals(true), nil
+}
+
+type payoffKey struct {
+	account int64
+	// job_id, the chrono sequence of postings without one
+	job string
+}
+
+// ReportPayoff lists the early payoffs read from r, one per account and
+// job_id, and writes them to w
+func ReportPayoff(r io.Reader, w io.Writer, opts Options) (PayoffResult, error) {
+	var res PayoffResult
+	rows := map[payoffKey]int{}
+	errs, err := Each(r, opts, func(tx Transaction) error {
+		row, ok, err := PayoffTransaction(tx)
+		if !ok {
+			return err
+		}
+		k := payoffKey{account: tx.AccountNumber.Val, job: tx.JobID.String()}
+		if tx.JobID.Null() {
+			k.job = tx.ChronoSequence.String()
+		}
+		i, seen := rows[k]
+		if !seen {
+			rows[k] = len(res.Rows)
+			res.Rows = append(res.Rows, row)
+			return err
+		}
+		payoff := &res.Rows[i]
+		payoff.Postings++
+		if compareStrings(row.ChronoSequence.String(), payoff.ChronoSequence.String()) < 0 {
+			payoff.ChronoSequence = row.ChronoSequence
+		}
+		return err
+	})
+	res.Errors = errs
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, PayoffColumns, res.Rows)
+}
+
+// PayoffColumns are the columns of the payoff report
+var PayoffColumns = []Column[PayoffRow]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(r PayoffRow) string { return int64Cell(r.AccountNumber) }},
+	{Name: "JobID", Width: 22, Value: func(r PayoffRow) string { return stringCell(r.JobID) }},
+	{Name: "ChronoSequence", Width: 25, Value: func(r PayoffRow) string { return stringCell(r.ChronoSequence) }},
+	{Name: "Postings", Width: 8, Numeric: true, Value: func(r PayoffRow) string { return strconv.Itoa(r.Postings) }},
+	{Name: "TransactionAmount", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return dec2Cell(r.TransactionAmount) }},
+	{Name: "AmountToClose", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return dec2Cell(r.AmountToClose) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return r.Principal().String() }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(r PayoffRow) string { return r.Interest().String() }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(r PayoffRow) string { return dec2Cell(r.PenaltyPayoffAmount) }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(r PayoffRow) string { return r.Vat().String() }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(r PayoffRow) string { return dec2Cell(r.FeePayoffAmount) }},
+	{Name: "DiscountAmount", Width: 14, Numeric: true, Value: func(r PayoffRow) string { return r.Discount().String() }},
+	{Name: "NetPayoffAmount", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return dec2Cell(r.NetPayoffAmount) }},
+	{Name: "ExpectedNetPayoffAmount", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return r.ExpectedNetPayoff().String() }},
+	{Name: "Delta", Width: 15, Numeric: true, Value: func(r PayoffRow) string { return r.Delta().String() }},
+}
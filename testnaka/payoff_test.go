@@ -0,0 +1,61 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePayoffInfo(t *testing.T) {
+	p, err := ParsePayoffInfo(map[string]interface{}{
+		"info_is_early_payoff":             "",
+		"is_early_payoff":                  "true",
+		"info_principal_payoff_amount":     "100.00",
+		"info_interest_payoff_amount":      "10.00",
+		"info_overridden_interest_amount":  "8.00",
+		"info_penalty_payoff_amount":       "1.50",
+		"info_vat_payoff_amount":           "0.70",
+		"info_fee_payoff_amount":           "",
+		"info_discount_interest_amount":    "2.00",
+		"info_net_payoff_amount":           "108.20",
+		"info_overridden_principal_amount": "",
+	})
+	assert.NoError(t, err)
+	assert.True(t, p.IsEarlyPayoff.Equals(true))
+	assert.True(t, p.FeePayoffAmount.Null())
+	assert.True(t, p.OverriddenPrincipalAmount.Null())
+	assert.Equal(t, "8.00", p.Interest().String())
+	assert.Equal(t, "108.20", p.ExpectedNetPayoff().String())
+	assert.True(t, p.Balanced())
+
+	p.NetPayoffAmount.Val = p.NetPayoffAmount.Val.Add(p.PenaltyPayoffAmount.Val)
+	assert.False(t, p.Balanced())
+	assert.Equal(t, "1.50", p.Delta().String())
+
+	_, err = ParsePayoffInfo(map[string]interface{}{"info_amount_to_close": 12.5})
+	assert.EqualError(t, err, "other_properties[info_amount_to_close] is float64, want string")
+	_, err = ParsePayoffInfo(map[string]interface{}{"info_amount_to_close": "abc"})
+	assert.Error(t, err)
+}
+
+func TestReportPayoff(t *testing.T) {
+	var out bytes.Buffer
+	res, err := ReportPayoff(openFixture(t), &out, DefaultOptions())
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	// 23 postings of three payoffs
+	if assert.Len(t, res.Rows, 3) {
+		assert.Equal(t, "190000069047", res.Rows[1].AccountNumber.String())
+		assert.Equal(t, "250115bbd442c0CD260489", res.Rows[1].JobID.String())
+		assert.Equal(t, 11, res.Rows[1].Postings)
+		assert.Equal(t, "250115110204477938089344A", res.Rows[1].ChronoSequence.String())
+	}
+	assert.Empty(t, res.Unbalanced())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "NetPayoffAmount|ExpectedNetPayoffAmount|Delta")
+	assert.Contains(t, out.String(), "|4122.33|30.94|0.00|290.73|0.00|0.00|4444.00|4444.00|0.00")
+}
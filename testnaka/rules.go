@@ -0,0 +1,197 @@
+package testnaka
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"gopkg.in/yaml.v3"
+)
+
+// Rules selects transactions declaratively. A transaction is kept when it
+// matches every include rule and none of the exclude rules.
+//
+//	exclude:
+//	  - field: last_updated_description
+//	    equals: "Entry=KAFKA : v1/dloan-interest/accrued-interest/history/bill-generation,"
+//	include:
+//	  - field: account_number
+//	    min: 190000000000
+//	    max: 199999999999
+type Rules struct {
+	Include []Rule `yaml:"include"`
+	Exclude []Rule `yaml:"exclude"`
+}
+
+// Rule tests one Transaction field, named by its json tag. Every condition
+// that is set must hold for the rule to match.
+type Rule struct {
+	Field string `yaml:"field"`
+	// Exact value
+	Equals *string `yaml:"equals,omitempty"`
+	// Any of these values
+	In []string `yaml:"in,omitempty"`
+	// Value starts with this prefix
+	Prefix string `yaml:"prefix,omitempty"`
+	// Inclusive integer range
+	Min *int64 `yaml:"min,omitempty"`
+	Max *int64 `yaml:"max,omitempty"`
+	// Inclusive date range, yyyy-mm-dd. Timestamps are compared by their date.
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"`
+}
+
+// DefaultRules skips the interest bill generation entries, as Main2 always did
+func DefaultRules() Rules {
+	entry := BillGenerationEntry
+	return Rules{Exclude: []Rule{{Field: "last_updated_description", Equals: &entry}}}
+}
+
+// LoadRules reads rules from a YAML file
+func LoadRules(path string) (Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	defer f.Close()
+	return DecodeRules(f)
+}
+
+// DecodeRules reads rules from YAML and validates them
+func DecodeRules(r io.Reader) (Rules, error) {
+	var rules Rules
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && err != io.EOF {
+		return rules, fmt.Errorf("decode rules: %w", err)
+	}
+	return rules, rules.Validate()
+}
+
+// Validate checks that every rule names a Transaction field and has a condition
+func (rs Rules) Validate() error {
+	for _, group := range []struct {
+		name  string
+		rules []Rule
+	}{{"include", rs.Include}, {"exclude", rs.Exclude}} {
+		for i, r := range group.rules {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("%s[%d]: %w", group.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	if _, ok := transactionFields()[r.Field]; !ok {
+		return fmt.Errorf("unknown field %q", r.Field)
+	}
+	if r.Equals == nil && r.In == nil && r.Prefix == "" && r.Min == nil && r.Max == nil && r.From == "" && r.To == "" {
+		return fmt.Errorf("rule on %q has no condition", r.Field)
+	}
+	for _, d := range []string{r.From, r.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := date.NewDates(d); err != nil {
+			return fmt.Errorf("rule on %q: invalid date %q", r.Field, d)
+		}
+	}
+	return nil
+}
+
+// Keep reports whether tx passes the rules
+func (rs Rules) Keep(tx Transaction) bool {
+	for _, r := range rs.Include {
+		if !r.Match(tx) {
+			return false
+		}
+	}
+	for _, r := range rs.Exclude {
+		if r.Match(tx) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether tx satisfies every condition of the rule. A null
+// field never matches.
+func (r Rule) Match(tx Transaction) bool {
+	v, ok := transactionField(tx, r.Field)
+	if !ok {
+		return false
+	}
+	if r.Equals != nil && v != *r.Equals {
+		return false
+	}
+	if r.In != nil && !containsStr(r.In, v) {
+		return false
+	}
+	if r.Prefix != "" && !strings.HasPrefix(v, r.Prefix) {
+		return false
+	}
+	if r.Min != nil || r.Max != nil {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || (r.Min != nil && n < *r.Min) || (r.Max != nil && n > *r.Max) {
+			return false
+		}
+	}
+	if r.From != "" || r.To != "" {
+		if len(v) < len(date.DateFormat) {
+			return false
+		}
+		d := v[:len(date.DateFormat)]
+		// yyyy-mm-dd sorts as a string
+		if (r.From != "" && d < r.From) || (r.To != "" && d > r.To) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsStr(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// transactionFields maps json tags of Transaction to their field index
+func transactionFields() map[string]int {
+	fields := map[string]int{}
+	t := reflect.TypeOf(Transaction{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag != "" && tag != "-" {
+			fields[tag] = i
+		}
+	}
+	return fields
+}
+
+var transactionFieldIndex = transactionFields()
+
+// transactionField returns the string value of the field tagged name
+func transactionField(tx Transaction, name string) (string, bool) {
+	i, ok := transactionFieldIndex[name]
+	if !ok {
+		return "", false
+	}
+	v := reflect.ValueOf(tx).Field(i).Interface()
+	if n, ok := v.(null.Nuller); ok && n.Null() {
+		return "", false
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return fmt.Sprint(v), true
+}
@@ -0,0 +1,70 @@
+package testnaka
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+const rulesYAML = `
+include:
+  - field: account_number
+    min: 190000000000
+    max: 199999999999
+  - field: transaction_date
+    from: 2025-01-01
+    to: 2025-01-31
+exclude:
+  - field: last_updated_description
+    prefix: "Entry=KAFKA : v1/dloan-interest/"
+  - field: job_id
+    in: ["250115bc5457fdCD909972"]
+`
+
+func TestDecodeRules(t *testing.T) {
+	rules, err := DecodeRules(strings.NewReader(rulesYAML))
+	assert.NoError(t, err)
+	assert.Len(t, rules.Include, 2)
+	assert.Len(t, rules.Exclude, 2)
+	assert.Equal(t, "2025-01-01", rules.Include[1].From)
+
+	tx := Transaction{
+		TransactionDate:        mustDate(t, "2025-01-15"),
+		JobID:                  null.NewString("250115d39a2b5fCD289462"),
+		AccountNumber:          null.NewInt64(190000010476),
+		LastUpdatedDescription: null.NewString("Entry=KAFKA : v1/dloan-transaction/transactions/deposit-for-repayment,"),
+	}
+	assert.True(t, rules.Keep(tx))
+
+	excluded := tx
+	excluded.JobID = null.NewString("250115bc5457fdCD909972")
+	assert.False(t, rules.Keep(excluded))
+
+	outOfRange := tx
+	outOfRange.AccountNumber = null.NewInt64(290000285348)
+	assert.False(t, rules.Keep(outOfRange))
+
+	noDate := tx
+	noDate.TransactionDate = null.Date{}
+	assert.False(t, rules.Keep(noDate))
+}
+
+func TestDecodeRules_invalid(t *testing.T) {
+	for _, in := range []string{
+		"include:\n  - field: no_such_field\n    equals: x\n",
+		"exclude:\n  - field: job_id\n",
+		"exclude:\n  - field: transaction_date\n    from: 15/01/2025\n",
+		"exclude:\n  - field: job_id\n    startswith: x\n",
+	} {
+		_, err := DecodeRules(strings.NewReader(in))
+		assert.Error(t, err, in)
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	res, err := Extract(openFixture(t), Options{Rules: DefaultRules()})
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 252)
+}
@@ -0,0 +1,370 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// JSON value types a schema field can declare
+type FieldType string
+
+const (
+	TypeString FieldType = "string"
+	TypeNumber FieldType = "number"
+	TypeBool   FieldType = "bool"
+	TypeObject FieldType = "object"
+	TypeArray  FieldType = "array"
+)
+
+// FieldSchema declares one key of a message or of its other_properties
+type FieldSchema struct {
+	Type     FieldType
+	Required bool
+}
+
+// MessageSchema declares the keys expected in the message of one event code
+type MessageSchema struct {
+	Message         map[string]FieldSchema
+	OtherProperties map[string]FieldSchema
+}
+
+// Sections of a message a drift can be found in
+const (
+	SectionEventCode       = "event_code"
+	SectionMessage         = "message"
+	SectionOtherProperties = "other_properties"
+)
+
+// Kinds of drift between a message and its schema
+type DriftKind string
+
+const (
+	DriftUnknownKey   DriftKind = "unknown_key"
+	DriftMissingKey   DriftKind = "missing_key"
+	DriftTypeMismatch DriftKind = "type_mismatch"
+	DriftInvalidJSON  DriftKind = "invalid_json"
+	DriftUnknownEvent DriftKind = "unknown_event_code"
+)
+
+// Strictness decides which drifts fail a validation run
+type Strictness int
+
+const (
+	// StrictNone only reports drifts
+	StrictNone Strictness = iota
+	// StrictRequired fails on missing required keys, type mismatches and undecodable messages
+	StrictRequired
+	// StrictAll also fails on unknown keys and unknown event codes
+	StrictAll
+)
+
+// ErrSchemaDrift is returned by SchemaReport.Err when the strictness level is exceeded
+var ErrSchemaDrift = errors.New("schema drift")
+
+// ParseStrictness parses none, required or all
+func ParseStrictness(s string) (Strictness, error) {
+	switch s {
+	case "none", "":
+		return StrictNone, nil
+	case "required":
+		return StrictRequired, nil
+	case "all":
+		return StrictAll, nil
+	}
+	return StrictNone, fmt.Errorf("unknown strictness %q, want none, required or all", s)
+}
+
+func (s Strictness) fails(kind DriftKind) bool {
+	switch kind {
+	case DriftMissingKey, DriftTypeMismatch, DriftInvalidJSON:
+		return s >= StrictRequired
+	case DriftUnknownKey, DriftUnknownEvent:
+		return s >= StrictAll
+	}
+	return false
+}
+
+// other_properties keys shared by every event code
+var commonOtherProperties = map[string]FieldSchema{
+	"is_awaiting_backdate":      {Type: TypeString, Required: true},
+	"is_clear_pending":          {Type: TypeString, Required: true},
+	"original_transaction_date": {Type: TypeString, Required: true},
+	"ref1":                      {Type: TypeString, Required: true},
+	"ref2":                      {Type: TypeString, Required: true},
+	"repayment_by":              {Type: TypeString, Required: true},
+	"requested_service":         {Type: TypeString, Required: true},
+	"transaction_type":          {Type: TypeString, Required: true},
+	"adjustment_flag":           {Type: TypeString},
+	"original_adjustment_flag":  {Type: TypeString},
+	"is_payoff":                 {Type: TypeString},
+	"repayment_reference":       {Type: TypeString},
+	"channel":                   {Type: TypeString},
+	"is_early_payoff":           {Type: TypeString},
+}
+
+// info_* keys sent with early payoff transactions
+var payoffInfoKeys = []string{
+	"info_amount_to_close",
+	"info_discount_interest_amount",
+	"info_early_interest_payoff_amount",
+	"info_fee_payoff_amount",
+	"info_interest_payoff_amount",
+	"info_is_early_payoff",
+	"info_net_payoff_amount",
+	"info_overridden_discount_amount",
+	"info_overridden_interest_amount",
+	"info_overridden_principal_amount",
+	"info_overridden_vat_amount",
+	"info_penalty_payoff_amount",
+	"info_principal_payoff_amount",
+	"info_transaction_amount",
+	"info_transaction_balance",
+	"info_transaction_type",
+	"info_unpaid_fee_amount",
+	"info_unpaid_interest_amount",
+	"info_unpaid_penalty_amount",
+	"info_vat_payoff_amount",
+}
+
+// message keys of due_bills and others
+var amountMessage = map[string]FieldSchema{
+	"account_number":    {Type: TypeNumber, Required: true},
+	"account_sequence":  {Type: TypeNumber, Required: true},
+	"principal_amount":  {Type: TypeNumber, Required: true},
+	"interest_amount":   {Type: TypeNumber, Required: true},
+	"penalty_amount":    {Type: TypeNumber, Required: true},
+	"vat_amount":        {Type: TypeNumber, Required: true},
+	"effective_date":    {Type: TypeString, Required: true},
+	"channel_post_date": {Type: TypeString, Required: true},
+	"currency_code":     {Type: TypeString, Required: true},
+	"service_branch":    {Type: TypeNumber, Required: true},
+	"other_properties":  {Type: TypeObject, Required: true},
+}
+
+// DefaultSchemas declares the messages dloan-payment publishes today
+var DefaultSchemas = map[string]MessageSchema{
+	"due_bills": {
+		Message: amountMessage,
+		OtherProperties: withOtherProperties(map[string]FieldSchema{
+			"bills":                {Type: TypeString},
+			"penalties":            {Type: TypeString},
+			"oldest_bill_due_date": {Type: TypeString},
+			"oldest_stmt_due_date": {Type: TypeString},
+		}),
+	},
+	"fee": {
+		Message: map[string]FieldSchema{
+			"fee_amount":       {Type: TypeNumber, Required: true},
+			"service_branch":   {Type: TypeNumber, Required: true},
+			"other_properties": {Type: TypeObject, Required: true},
+		},
+		OtherProperties: withOtherProperties(map[string]FieldSchema{
+			"fee": {Type: TypeString, Required: true},
+		}),
+	},
+	"others": {
+		Message: amountMessage,
+		OtherProperties: withOtherProperties(map[string]FieldSchema{
+			"penalties":       {Type: TypeString},
+			"advance_payment": {Type: TypeString},
+		}),
+	},
+}
+
+// withOtherProperties adds the common and payoff keys to fields
+func withOtherProperties(fields map[string]FieldSchema) map[string]FieldSchema {
+	for k, v := range commonOtherProperties {
+		fields[k] = v
+	}
+	for _, k := range payoffInfoKeys {
+		fields[k] = FieldSchema{Type: TypeString}
+	}
+	return fields
+}
+
+// Drift counts the transactions that disagree with the schema in the same way
+type Drift struct {
+	EventCode string
+	Section   string
+	Key       string
+	Kind      DriftKind
+	Expected  FieldType
+	Actual    FieldType
+	Count     int
+	// First chrono_sequences that showed the drift
+	Samples []string
+}
+
+// SchemaReport is the outcome of a schema validation run
+type SchemaReport struct {
+	Checked int
+	Drifts  []Drift
+}
+
+// Count returns the number of occurrences of kind
+func (r SchemaReport) Count(kind DriftKind) int {
+	n := 0
+	for _, d := range r.Drifts {
+		if d.Kind == kind {
+			n += d.Count
+		}
+	}
+	return n
+}
+
+// Err returns an ErrSchemaDrift when a drift fails the given strictness
+func (r SchemaReport) Err(level Strictness) error {
+	var failed []string
+	for _, d := range r.Drifts {
+		if level.fails(d.Kind) {
+			failed = append(failed, fmt.Sprintf("%s %s.%s %s x%d", d.EventCode, d.Section, d.Key, d.Kind, d.Count))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSchemaDrift, strings.Join(failed, "; "))
+}
+
+// SchemaValidator checks transactions one at a time against declared schemas
+type SchemaValidator struct {
+	schemas    map[string]MessageSchema
+	sampleSize int
+	checked    int
+	drifts     map[driftKey]*Drift
+}
+
+type driftKey struct {
+	eventCode, section, key string
+	kind                    DriftKind
+	actual                  FieldType
+}
+
+// NewSchemaValidator keeps up to sampleSize chrono_sequences per drift
+func NewSchemaValidator(schemas map[string]MessageSchema, sampleSize int) *SchemaValidator {
+	return &SchemaValidator{schemas: schemas, sampleSize: sampleSize, drifts: map[driftKey]*Drift{}}
+}
+
+// ValidateSchema checks every transaction of body against schemas
+func ValidateSchema(body Body, schemas map[string]MessageSchema) SchemaReport {
+	v := NewSchemaValidator(schemas, 3)
+	for _, tx := range body.ReqBody {
+		v.Check(tx)
+	}
+	return v.Report()
+}
+
+// Check records the drifts of one transaction
+func (v *SchemaValidator) Check(tx Transaction) {
+	v.checked++
+	eventCode := tx.EventCode.String()
+	schema, ok := v.schemas[eventCode]
+	if !ok {
+		v.add(tx, "", SectionEventCode, eventCode, DriftUnknownEvent, "", "")
+		return
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(tx.Message.String()), &msg); err != nil {
+		v.add(tx, eventCode, SectionMessage, "", DriftInvalidJSON, TypeObject, "")
+		return
+	}
+	v.checkFields(tx, eventCode, SectionMessage, schema.Message, msg)
+
+	if props, ok := msg["other_properties"].(map[string]interface{}); ok {
+		v.checkFields(tx, eventCode, SectionOtherProperties, schema.OtherProperties, props)
+	}
+}
+
+func (v *SchemaValidator) checkFields(tx Transaction, eventCode, section string, fields map[string]FieldSchema, values map[string]interface{}) {
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			v.add(tx, eventCode, section, key, DriftUnknownKey, "", jsonType(value))
+			continue
+		}
+		if actual := jsonType(value); actual != "" && actual != field.Type {
+			v.add(tx, eventCode, section, key, DriftTypeMismatch, field.Type, actual)
+		}
+	}
+	for key, field := range fields {
+		if _, ok := values[key]; !ok && field.Required {
+			v.add(tx, eventCode, section, key, DriftMissingKey, field.Type, "")
+		}
+	}
+}
+
+func (v *SchemaValidator) add(tx Transaction, eventCode, section, key string, kind DriftKind, expected, actual FieldType) {
+	k := driftKey{eventCode: eventCode, section: section, key: key, kind: kind, actual: actual}
+	d, ok := v.drifts[k]
+	if !ok {
+		d = &Drift{EventCode: eventCode, Section: section, Key: key, Kind: kind, Expected: expected, Actual: actual}
+		v.drifts[k] = d
+	}
+	d.Count++
+	if len(d.Samples) < v.sampleSize {
+		d.Samples = append(d.Samples, tx.ChronoSequence.String())
+	}
+}
+
+// Report returns the drifts found so far, sorted by event code, section,
+// key, kind and actual type
+func (v *SchemaValidator) Report() SchemaReport {
+	report := SchemaReport{Checked: v.checked}
+	for _, d := range v.drifts {
+		report.Drifts = append(report.Drifts, *d)
+	}
+	sort.Slice(report.Drifts, func(i, j int) bool {
+		a, b := report.Drifts[i], report.Drifts[j]
+		if a.EventCode != b.EventCode {
+			return a.EventCode < b.EventCode
+		}
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Actual < b.Actual
+	})
+	return report
+}
+
+// jsonType names the type of a value decoded by encoding/json, null has no type
+func jsonType(value interface{}) FieldType {
+	switch value.(type) {
+	case string:
+		return TypeString
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBool
+	case map[string]interface{}:
+		return TypeObject
+	case []interface{}:
+		return TypeArray
+	}
+	return ""
+}
+
+// WriteSchemaReport writes report as an aligned table
+func WriteSchemaReport(w io.Writer, report SchemaReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "checked %d transactions, %d drifts\n", report.Checked, len(report.Drifts))
+	if len(report.Drifts) > 0 {
+		fmt.Fprintln(tw, "EventCode\tSection\tKey\tKind\tExpected\tActual\tCount\tSamples")
+	}
+	for _, d := range report.Drifts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			d.EventCode, d.Section, d.Key, d.Kind, d.Expected, d.Actual, d.Count, strings.Join(d.Samples, ","))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,94 @@
+package testnaka
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSchema_fixture(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	report := ValidateSchema(body, DefaultSchemas)
+	assert.Equal(t, 258, report.Checked)
+	assert.Empty(t, report.Drifts)
+	assert.NoError(t, report.Err(StrictAll))
+}
+
+func TestValidateSchema_drift(t *testing.T) {
+	body := Body{ReqBody: []Transaction{
+		{
+			ChronoSequence: null.NewString("C1"),
+			EventCode:      null.NewString("fee"),
+			Message:        null.NewString(`{"fee_amount":"100.00","service_branch":0,"other_properties":{"fee":"[]","penalty":"[]"}}`),
+		},
+		{
+			ChronoSequence: null.NewString("C2"),
+			EventCode:      null.NewString("fee"),
+			Message:        null.NewString(`{"fee_amount":100.00,"other_properties":{"fee":"[]","penalty":"[]"}}`),
+		},
+		{
+			ChronoSequence: null.NewString("C3"),
+			EventCode:      null.NewString("write_off"),
+			Message:        null.NewString(`{}`),
+		},
+	}}
+	schemas := map[string]MessageSchema{"fee": {
+		Message: map[string]FieldSchema{
+			"fee_amount":       {Type: TypeNumber, Required: true},
+			"service_branch":   {Type: TypeNumber, Required: true},
+			"other_properties": {Type: TypeObject, Required: true},
+		},
+		OtherProperties: map[string]FieldSchema{"fee": {Type: TypeString, Required: true}},
+	}}
+	report := ValidateSchema(body, schemas)
+
+	assert.Equal(t, 2, report.Count(DriftUnknownKey))
+	assert.Equal(t, 1, report.Count(DriftMissingKey))
+	assert.Equal(t, 1, report.Count(DriftTypeMismatch))
+	assert.Equal(t, 1, report.Count(DriftUnknownEvent))
+	for _, d := range report.Drifts {
+		if d.Kind == DriftUnknownKey {
+			assert.Equal(t, "penalty", d.Key)
+			assert.Equal(t, []string{"C1", "C2"}, d.Samples)
+		}
+	}
+
+	assert.NoError(t, report.Err(StrictNone))
+	assert.True(t, errors.Is(report.Err(StrictRequired), ErrSchemaDrift))
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteSchemaReport(&out, report))
+	assert.Contains(t, out.String(), "checked 3 transactions")
+}
+
+func TestValidateSchema_driftOrder(t *testing.T) {
+	var body Body
+	for i, amount := range []string{`"100.00"`, `true`, `[]`, `{}`} {
+		body.ReqBody = append(body.ReqBody, Transaction{
+			ChronoSequence: null.NewString(string(rune('A' + i))),
+			EventCode:      null.NewString("fee"),
+			Message:        null.NewString(`{"fee_amount":` + amount + `}`),
+		})
+	}
+	schemas := map[string]MessageSchema{"fee": {Message: map[string]FieldSchema{"fee_amount": {Type: TypeNumber}}}}
+	want := []FieldType{TypeArray, TypeBool, TypeObject, TypeString}
+	for i := 0; i < 20; i++ {
+		var actual []FieldType
+		for _, d := range ValidateSchema(body, schemas).Drifts {
+			actual = append(actual, d.Actual)
+		}
+		assert.Equal(t, want, actual)
+	}
+}
+
+func TestParseStrictness(t *testing.T) {
+	s, err := ParseStrictness("required")
+	assert.NoError(t, err)
+	assert.Equal(t, StrictRequired, s)
+	_, err = ParseStrictness("loose")
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package testnaka
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+)
+
+// Stage is the IFRS 9 impairment stage of an account
+type Stage int
+
+const (
+	// Stage1 is performing
+	Stage1 Stage = 1
+	// Stage2 has a significant increase in credit risk
+	Stage2 Stage = 2
+	// Stage3 is credit impaired
+	Stage3 Stage = 3
+	// StageNew is the from stage of accounts without a transaction by the
+	// earlier date of a migration
+	StageNew Stage = 0
+)
+
+// Stages lists the stages in order
+var Stages = []Stage{Stage1, Stage2, Stage3}
+
+func (s Stage) String() string {
+	if s == StageNew {
+		return "new"
+	}
+	return strconv.Itoa(int(s))
+}
+
+// StagingThresholds are the days past due beyond which an account moves to
+// stage 2 and stage 3
+type StagingThresholds struct {
+	Stage2Days int64
+	Stage3Days int64
+}
+
+// DefaultStagingThresholds are the IFRS 9 presumptions: more than 30 days
+// past due is stage 2, more than 90 stage 3
+func DefaultStagingThresholds() StagingThresholds {
+	return StagingThresholds{Stage2Days: 30, Stage3Days: 90}
+}
+
+// Validate reports thresholds that cannot order the stages
+func (t StagingThresholds) Validate() error {
+	if t.Stage2Days < 0 || t.Stage3Days <= t.Stage2Days {
+		return fmt.Errorf("staging thresholds: want 0 <= stage 2 days (%d) < stage 3 days (%d)", t.Stage2Days, t.Stage3Days)
+	}
+	return nil
+}
+
+// Classify returns the stage of an account days past due
+func (t StagingThresholds) Classify(days int64) Stage {
+	switch {
+	case days > t.Stage3Days:
+		return Stage3
+	case days > t.Stage2Days:
+		return Stage2
+	}
+	return Stage1
+}
+
+// AccountStage is the aging of an account and the stage it falls in
+type AccountStage struct {
+	AccountAging
+	Stage Stage
+}
+
+// StagingResult holds the staged accounts, most days past due first
+type StagingResult struct {
+	Accounts []AccountStage
+	Errors   []TransactionError
+}
+
+// StageAccounts ages the accounts of body as of asOf and classifies them
+func StageAccounts(body Body, opts Options, asOf date.Date, th StagingThresholds) StagingResult {
+	t := newAgingTracker(asOf)
+	errs := eachTransaction(body, opts, t.add)
+	return stageAging(t.result(errs), th)
+}
+
+// ReportStages stages the accounts of the response read from r and writes
+// them to w
+func ReportStages(r io.Reader, w io.Writer, opts Options, asOf date.Date, th StagingThresholds) (StagingResult, error) {
+	t := newAgingTracker(asOf)
+	errs, err := Each(r, opts, t.add)
+	res := stageAging(t.result(errs), th)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, AccountStageColumns, res.Accounts)
+}
+
+func stageAging(aging AgingResult, th StagingThresholds) StagingResult {
+	res := StagingResult{Errors: aging.Errors}
+	for _, a := range aging.Accounts {
+		res.Accounts = append(res.Accounts, AccountStage{AccountAging: a, Stage: th.Classify(a.DaysPastDue)})
+	}
+	return res
+}
+
+// StageMigration is one row of the migration matrix: the accounts in From
+// at the earlier date by their stage at the later one
+type StageMigration struct {
+	From Stage
+	// Accounts by stage at the later date, Stages order
+	To [3]int
+	// Unpaid amount of the row's accounts at the later date
+	UnpaidAmount decimal.Dec2
+}
+
+// Accounts is the number of accounts in From at the earlier date
+func (m StageMigration) Accounts() int {
+	return m.To[0] + m.To[1] + m.To[2]
+}
+
+// MigrationResult holds one row per stage in Stages order, then the
+// StageNew row of the accounts that did not exist at the earlier date
+type MigrationResult struct {
+	Rows   []StageMigration
+	Errors []TransactionError
+}
+
+// MigrateStages stages the accounts of body as of from and as of to and
+// counts how they moved between the two dates. Each date sees only the
+// transactions dated on or before it.
+func MigrateStages(body Body, opts Options, from, to date.Date, th StagingThresholds) MigrationResult {
+	t := newMigrationTracker(from, to)
+	errs := eachTransaction(body, opts, t.add)
+	return t.result(errs, th)
+}
+
+// ReportStageMigration writes the migration matrix of the response read
+// from r between from and to to w
+func ReportStageMigration(r io.Reader, w io.Writer, opts Options, from, to date.Date, th StagingThresholds) (MigrationResult, error) {
+	t := newMigrationTracker(from, to)
+	errs, err := Each(r, opts, t.add)
+	res := t.result(errs, th)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, StageMigrationColumns, res.Rows)
+}
+
+// migrationTracker ages the accounts as of both dates of a migration
+type migrationTracker struct {
+	from, to *agingTracker
+}
+
+func newMigrationTracker(from, to date.Date) migrationTracker {
+	return migrationTracker{from: newAgingTracker(from), to: newAgingTracker(to)}
+}
+
+func (t migrationTracker) add(tx Transaction) error {
+	if err := t.from.add(tx); err != nil {
+		return err
+	}
+	return t.to.add(tx)
+}
+
+func (t migrationTracker) result(errs []TransactionError, th StagingThresholds) MigrationResult {
+	res := MigrationResult{Errors: errs}
+	for _, s := range Stages {
+		res.Rows = append(res.Rows, StageMigration{From: s})
+	}
+	res.Rows = append(res.Rows, StageMigration{From: StageNew})
+	before := map[int64]Stage{}
+	for _, a := range stageAging(t.from.result(nil), th).Accounts {
+		before[a.AccountNumber.Val] = a.Stage
+	}
+	for _, a := range stageAging(t.to.result(nil), th).Accounts {
+		row := &res.Rows[len(Stages)]
+		if from, ok := before[a.AccountNumber.Val]; ok {
+			row = &res.Rows[from-1]
+		}
+		row.To[a.Stage-1]++
+		row.UnpaidAmount = row.UnpaidAmount.Add(a.UnpaidAmount())
+	}
+	return res
+}
+
+// AccountStageColumns are the columns of the per-account stage report
+var AccountStageColumns = []Column[AccountStage]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(a AccountStage) string { return int64Cell(a.AccountNumber) }},
+	{Name: "OldestDueDate", Width: 10, Value: func(a AccountStage) string { return dateCell(a.OldestDueDate) }},
+	{Name: "DaysPastDue", Width: 6, Numeric: true, Value: func(a AccountStage) string { return strconv.FormatInt(a.DaysPastDue, 10) }},
+	{Name: "Stage", Width: 5, Numeric: true, Value: func(a AccountStage) string { return a.Stage.String() }},
+	{Name: "UnpaidAmount", Width: 15, Numeric: true, Value: func(a AccountStage) string { return a.UnpaidAmount().String() }},
+}
+
+// StageMigrationColumns are the columns of the stage migration matrix
+var StageMigrationColumns = []Column[StageMigration]{
+	{Name: "FromStage", Width: 5, Numeric: true, Value: func(m StageMigration) string { return m.From.String() }},
+	{Name: "ToStage1", Width: 8, Numeric: true, Value: func(m StageMigration) string { return strconv.Itoa(m.To[0]) }},
+	{Name: "ToStage2", Width: 8, Numeric: true, Value: func(m StageMigration) string { return strconv.Itoa(m.To[1]) }},
+	{Name: "ToStage3", Width: 8, Numeric: true, Value: func(m StageMigration) string { return strconv.Itoa(m.To[2]) }},
+	{Name: "Accounts", Width: 8, Numeric: true, Value: func(m StageMigration) string { return strconv.Itoa(m.Accounts()) }},
+	{Name: "UnpaidAmount", Width: 15, Numeric: true, Value: func(m StageMigration) string { return m.UnpaidAmount.String() }},
+}
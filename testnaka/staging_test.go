@@ -0,0 +1,113 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/date"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStagingThresholds(t *testing.T) {
+	th := DefaultStagingThresholds()
+	assert.NoError(t, th.Validate())
+	for days, want := range map[int64]Stage{0: Stage1, 30: Stage1, 31: Stage2, 90: Stage2, 91: Stage3} {
+		assert.Equal(t, want, th.Classify(days), days)
+	}
+
+	th = StagingThresholds{Stage2Days: 0, Stage3Days: 60}
+	assert.NoError(t, th.Validate())
+	assert.Equal(t, Stage2, th.Classify(1))
+	assert.Equal(t, Stage3, th.Classify(61))
+
+	assert.Error(t, StagingThresholds{Stage2Days: 90, Stage3Days: 30}.Validate())
+	assert.Error(t, StagingThresholds{Stage2Days: -1, Stage3Days: 30}.Validate())
+}
+
+func TestReportStages(t *testing.T) {
+	asOf, _ := date.NewDates("2025-02-28")
+	var out bytes.Buffer
+	res, err := ReportStages(openFixture(t), &out, DefaultOptions(), asOf, DefaultStagingThresholds())
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	assert.True(t, strings.HasPrefix(out.String(), "AccountNumber|OldestDueDate|DaysPastDue|Stage|UnpaidAmount\n"+
+		"290000163536|2024-11-20|100|3|5129.69\n"+
+		"290000158400|2024-12-15|75|2|164.81\n"))
+
+	stages := map[Stage]int{}
+	for _, a := range res.Accounts {
+		stages[a.Stage]++
+	}
+	assert.Equal(t, 1, stages[Stage3])
+	assert.Equal(t, 3, stages[Stage2])
+}
+
+func TestReportStageMigration(t *testing.T) {
+	from, _ := date.NewDates("2025-01-31")
+	to, _ := date.NewDates("2025-02-28")
+	var out bytes.Buffer
+	res, err := ReportStageMigration(openFixture(t), &out, DefaultOptions(), from, to, DefaultStagingThresholds())
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Rows, 4) {
+		// the two accounts 16 days past due cross 30 days, the one 72
+		// days past due crosses 90
+		assert.Equal(t, [3]int{85, 2, 0}, res.Rows[0].To)
+		assert.Equal(t, "701.94", res.Rows[0].UnpaidAmount.String())
+		assert.Equal(t, [3]int{0, 1, 1}, res.Rows[1].To)
+		assert.Equal(t, 0, res.Rows[2].Accounts())
+		assert.Equal(t, 0, res.Rows[3].Accounts())
+	}
+	assert.True(t, strings.HasPrefix(out.String(), "FromStage|ToStage1|ToStage2|ToStage3|Accounts|UnpaidAmount\n1|"))
+	assert.True(t, strings.HasSuffix(out.String(), "\n3|0|0|0|0|0.00\nnew|0|0|0|0|0.00\n"))
+
+	// no account had a transaction by 2025-01-14
+	from, _ = date.NewDates("2025-01-14")
+	res, err = ReportStageMigration(openFixture(t), &out, DefaultOptions(), from, to, DefaultStagingThresholds())
+	assert.NoError(t, err)
+	if assert.Len(t, res.Rows, 4) {
+		assert.Equal(t, 0, res.Rows[0].Accounts())
+		assert.Equal(t, StageNew, res.Rows[3].From)
+		assert.Equal(t, 89, res.Rows[3].Accounts())
+	}
+}
+
+func TestMigrateStages_datedTransactions(t *testing.T) {
+	tx := func(account int64, chrono, txDate, props string) Transaction {
+		d, _ := null.NewDates(txDate)
+		return Transaction{
+			TransactionDate: d,
+			AccountNumber:   null.NewInt64(account),
+			ChronoSequence:  null.NewString(chrono),
+			EventCode:       null.NewString("due_bills"),
+			Message:         null.NewString(`{"other_properties":{` + props + `}}`),
+		}
+	}
+	unpaid := func(due string) string {
+		return `"bills":"[{\"bill_sequence\":1,\"bill_due_date\":\"` + due + `\",\"unpaid_principal_amount\":50.00}]"`
+	}
+	body := Body{ReqBody: []Transaction{
+		// stage 3 at from, paid off between the two dates
+		tx(1, "250110000001", "2025-01-10", unpaid("2024-10-01")),
+		tx(1, "250210000001", "2025-02-10", `"oldest_bill_due_date":"9999-12-31","oldest_stmt_due_date":"9999-12-31"`),
+		// first billed between the two dates
+		tx(2, "250205000002", "2025-02-05", unpaid("2025-01-01")),
+	}}
+
+	from, _ := date.NewDates("2025-01-31")
+	to, _ := date.NewDates("2025-02-28")
+	res := MigrateStages(body, Options{}, from, to, DefaultStagingThresholds())
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Rows, 4) {
+		assert.Equal(t, 0, res.Rows[0].Accounts())
+		assert.Equal(t, 0, res.Rows[1].Accounts())
+		assert.Equal(t, [3]int{1, 0, 0}, res.Rows[2].To)
+		assert.True(t, res.Rows[2].UnpaidAmount.IsZero())
+		// not a stage 1 account at from
+		assert.Equal(t, StageNew, res.Rows[3].From)
+		assert.Equal(t, [3]int{0, 1, 0}, res.Rows[3].To)
+		assert.Equal(t, "50.00", res.Rows[3].UnpaidAmount.String())
+	}
+}
@@ -0,0 +1,152 @@
+package testnaka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Decoder reads the transactions of a publishMessageDetail response one at a
+// time, so memory stays flat however large rs_body is
+type Decoder struct {
+	dec *json.Decoder
+	// index of the next transaction in rs_body
+	index int
+	state decoderState
+}
+
+type decoderState int
+
+const (
+	stateStart decoderState = iota
+	stateKeys
+	stateBody
+	stateDone
+)
+
+// NewDecoder returns a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Index is the rs_body position of the transaction last returned by Next
+func (d *Decoder) Index() int {
+	return d.index - 1
+}
+
+// Next returns the next transaction of rs_body, io.EOF after the last one
+func (d *Decoder) Next() (Transaction, error) {
+	var tx Transaction
+	for {
+		switch d.state {
+		case stateStart:
+			if err := d.expect(json.Delim('{')); err != nil {
+				return tx, err
+			}
+			d.state = stateKeys
+
+		case stateKeys:
+			if !d.dec.More() {
+				if err := d.expect(json.Delim('}')); err != nil {
+					return tx, err
+				}
+				d.state = stateDone
+				continue
+			}
+			tok, err := d.dec.Token()
+			if err != nil {
+				return tx, d.wrap(err)
+			}
+			if key, _ := tok.(string); key == "rs_body" {
+				if err := d.openBody(); err != nil {
+					return tx, err
+				}
+				continue
+			}
+			// skip any other member
+			var skip json.RawMessage
+			if err := d.dec.Decode(&skip); err != nil {
+				return tx, d.wrap(err)
+			}
+
+		case stateBody:
+			if !d.dec.More() {
+				if err := d.expect(json.Delim(']')); err != nil {
+					return tx, err
+				}
+				d.state = stateKeys
+				continue
+			}
+			if err := d.dec.Decode(&tx); err != nil {
+				return tx, d.wrap(err)
+			}
+			d.index++
+			return tx, nil
+
+		case stateDone:
+			return tx, io.EOF
+		}
+	}
+}
+
+// openBody enters the rs_body array, a null rs_body holds no transactions
+func (d *Decoder) openBody() error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return d.wrap(err)
+	}
+	switch tok {
+	case json.Delim('['):
+		d.state = stateBody
+	case nil:
+	default:
+		return fmt.Errorf("decode response: rs_body is %v, want array", tok)
+	}
+	return nil
+}
+
+func (d *Decoder) expect(want json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return d.wrap(err)
+	}
+	if tok != want {
+		return fmt.Errorf("decode response: got %v, want %v", tok, want)
+	}
+	return nil
+}
+
+func (d *Decoder) wrap(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("decode response: %w", err)
+}
+
+// Each streams the transactions of r and calls fn for those kept by opts.
+// Errors returned by fn are collected per transaction, only a broken
+// response ends the walk.
+func Each(r io.Reader, opts Options, fn func(tx Transaction) error) ([]TransactionError, error) {
+	var errs []TransactionError
+	d := NewDecoder(r)
+	for {
+		tx, err := d.Next()
+		if err == io.EOF {
+			return errs, nil
+		}
+		if err != nil {
+			return errs, err
+		}
+		if !opts.Keep(tx) {
+			continue
+		}
+		if err := opts.maskThen(tx, fn); err != nil {
+			errs = append(errs, TransactionError{
+				Index:          d.Index(),
+				ChronoSequence: tx.ChronoSequence.String(),
+				EventCode:      tx.EventCode.String(),
+				Err:            err,
+			})
+		}
+	}
+}
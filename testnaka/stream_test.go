@@ -0,0 +1,129 @@
+package testnaka
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	in := `{"rs_header":{"status":"ok","list":[1,2]},"rs_body":[
+		{"chrono_sequence":"A1","account_number":1},
+		{"chrono_sequence":"A2","account_number":2}
+	],"rs_footer":null}`
+	d := NewDecoder(strings.NewReader(in))
+
+	tx, err := d.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "A1", tx.ChronoSequence.String())
+	assert.Equal(t, 0, d.Index())
+	tx, err = d.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "A2", tx.ChronoSequence.String())
+	assert.Equal(t, 1, d.Index())
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoder_emptyAndNull(t *testing.T) {
+	for _, in := range []string{`{}`, `{"rs_body":null}`, `{"rs_body":[]}`} {
+		_, err := NewDecoder(strings.NewReader(in)).Next()
+		assert.Equal(t, io.EOF, err, in)
+	}
+}
+
+func TestDecoder_broken(t *testing.T) {
+	for _, in := range []string{``, `[]`, `{"rs_body":{}}`, `{"rs_body":[{"account_number":1}`, `{"rs_body":[{"account_number":"x"}]}`} {
+		d := NewDecoder(strings.NewReader(in))
+		var err error
+		for err == nil {
+			_, err = d.Next()
+		}
+		assert.NotEqual(t, io.EOF, err, in)
+	}
+}
+
+func TestEach_matchesDecodeBody(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	var streamed []Transaction
+	errs, err := Each(openFixture(t), Options{}, func(tx Transaction) error {
+		streamed = append(streamed, tx)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Equal(t, body.ReqBody, streamed)
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestReport_writeErrorStops(t *testing.T) {
+	_, err := Report(openFixture(t), failWriter{}, DefaultOptions())
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+// largeResponse repeats the fixture transactions n times
+func largeResponse(b *testing.B, n int) []byte {
+	b.Helper()
+	content, err := os.ReadFile(ResponseFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var body Body
+	if err := json.Unmarshal(content, &body); err != nil {
+		b.Fatal(err)
+	}
+	var large Body
+	for i := 0; i < n; i++ {
+		large.ReqBody = append(large.ReqBody, body.ReqBody...)
+	}
+	out, err := json.Marshal(large)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return out
+}
+
+// BenchmarkReport_readAll is the approach Main2 started with: read the whole
+// response, unmarshal the whole Body, then write every row
+func BenchmarkReport_readAll(b *testing.B) {
+	in := largeResponse(b, 40)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		content, _ := io.ReadAll(bytes.NewReader(in))
+		var body Body
+		if err := json.Unmarshal(content, &body); err != nil {
+			b.Fatal(err)
+		}
+		res := ProcessBody(body, DefaultOptions())
+		if err := WriteRows(io.Discard, res.Rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReport_stream(b *testing.B) {
+	in := largeResponse(b, 40)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Report(bytes.NewReader(in), io.Discard, DefaultOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package testnaka
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// SummaryGroup selects the key totals are summed by
+type SummaryGroup string
+
+const (
+	GroupAccount     SummaryGroup = "account"
+	GroupDate        SummaryGroup = "date"
+	GroupAccountDate SummaryGroup = "account-date"
+)
+
+// ParseSummaryGroup parses account, date or account-date
+func ParseSummaryGroup(s string) (SummaryGroup, error) {
+	switch g := SummaryGroup(s); g {
+	case GroupAccount, GroupDate, GroupAccountDate:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown summary group %q, want account, date or account-date", s)
+}
+
+// Summary holds the totals of one group. Amounts are summed with
+// decimal.Dec2 so they match core banking to the satang.
+type Summary struct {
+	// Null when not grouped by account
+	AccountNumber null.Int64
+	// Null when not grouped by date
+	TransactionDate null.Date
+	Transactions    int
+	PrincipalAmount decimal.Dec2
+	InterestAmount  decimal.Dec2
+	PenaltyAmount   decimal.Dec2
+	VatAmount       decimal.Dec2
+	FeeAmount       decimal.Dec2
+	// principal, interest and penalty of others advance_payment. They are
+	// the breakdown of the message amounts, informational only.
+	AdvancePaymentAmount decimal.Dec2
+}
+
+// Total is the sum of the message amounts of the summary, advance_payment
+// is already part of them
+func (s Summary) Total() decimal.Dec2 {
+	return s.PrincipalAmount.Add(s.InterestAmount).Add(s.PenaltyAmount).Add(s.VatAmount).Add(s.FeeAmount)
+}
+
+// add accumulates the amounts of row
+func (s *Summary) add(row Row, advance *AdvancePayment) {
+	s.Transactions++
+	s.PrincipalAmount = s.PrincipalAmount.Add(row.PrincipalAmount.Val)
+	s.InterestAmount = s.InterestAmount.Add(row.InterestAmount.Val)
+	s.PenaltyAmount = s.PenaltyAmount.Add(row.PenaltyAmount.Val)
+	s.VatAmount = s.VatAmount.Add(row.VatAmount.Val)
+	s.FeeAmount = s.FeeAmount.Add(row.FeeAmount.Val)
+	if advance != nil {
+		s.AdvancePaymentAmount = s.AdvancePaymentAmount.Add(advance.PrincipalAmount.Val).
+			Add(advance.InterestAmount.Val).Add(advance.PenaltyAmount.Val)
+	}
+}
+
+// SummaryResult holds the totals per group, ordered by account then date
+type SummaryResult struct {
+	Summaries []Summary
+	Errors    []TransactionError
+}
+
+// Summarize totals the amounts of the kept transactions of body
+func Summarize(body Body, opts Options, by SummaryGroup) SummaryResult {
+	s := newSummarizer(opts, by)
+	errs := eachTransaction(body, opts, s.add)
+	return s.result(errs)
+}
+
+// summarizer accumulates totals per group
+type summarizer struct {
+	reg    *Registry
+	by     SummaryGroup
+	groups map[summaryKey]*Summary
+}
+
+type summaryKey struct {
+	account int64
+	date    string
+}
+
+func newSummarizer(opts Options, by SummaryGroup) *summarizer {
+	return &summarizer{reg: opts.registry(), by: by, groups: map[summaryKey]*Summary{}}
+}
+
+func (s *summarizer) add(tx Transaction) error {
+	row, err := s.reg.ProcessTransaction(tx)
+	if err != nil {
+		return err
+	}
+	var advance *AdvancePayment
+	if tx.EventCode.Equals("others") {
+		var msg OthersMessage
+		if err := decodeMessage(tx, &msg); err != nil {
+			return err
+		}
+		props, err := msg.DecodeOtherProperties()
+		if err != nil {
+			return err
+		}
+		advance = props.AdvancePayment
+	}
+
+	var k summaryKey
+	group := Summary{}
+	if s.by != GroupDate {
+		k.account = tx.AccountNumber.Val
+		group.AccountNumber = tx.AccountNumber
+	}
+	if s.by != GroupAccount {
+		k.date = dateCell(tx.TransactionDate)
+		group.TransactionDate = tx.TransactionDate
+	}
+	g, ok := s.groups[k]
+	if !ok {
+		g = &group
+		s.groups[k] = g
+	}
+	g.add(row, advance)
+	return nil
+}
+
+func (s *summarizer) result(errs []TransactionError) SummaryResult {
+	res := SummaryResult{Errors: errs}
+	for _, g := range s.groups {
+		res.Summaries = append(res.Summaries, *g)
+	}
+	sort.Slice(res.Summaries, func(i, j int) bool {
+		a, b := res.Summaries[i], res.Summaries[j]
+		if a.AccountNumber.Val != b.AccountNumber.Val {
+			return a.AccountNumber.Val < b.AccountNumber.Val
+		}
+		return dateCell(a.TransactionDate) < dateCell(b.TransactionDate)
+	})
+	return res
+}
+
+// ReportSummary summarizes the response read from r and writes the totals to w
+func ReportSummary(r io.Reader, w io.Writer, opts Options, by SummaryGroup) (SummaryResult, error) {
+	s := newSummarizer(opts, by)
+	errs, err := Each(r, opts, s.add)
+	res := s.result(errs)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, SummaryColumns, res.Summaries)
+}
+
+// SummaryColumns are the columns of the summary report
+var SummaryColumns = []Column[Summary]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(s Summary) string { return int64Cell(s.AccountNumber) }},
+	{Name: "TransactionDate", Width: 10, Value: func(s Summary) string { return dateCell(s.TransactionDate) }},
+	{Name: "Transactions", Width: 6, Numeric: true, Value: func(s Summary) string { return strconv.Itoa(s.Transactions) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(s Summary) string { return s.PrincipalAmount.String() }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(s Summary) string { return s.InterestAmount.String() }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(s Summary) string { return s.PenaltyAmount.String() }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(s Summary) string { return s.VatAmount.String() }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(s Summary) string { return s.FeeAmount.String() }},
+	{Name: "AdvancePaymentAmount", Width: 15, Numeric: true, Value: func(s Summary) string { return s.AdvancePaymentAmount.String() }},
+	{Name: "TotalAmount", Width: 15, Numeric: true, Value: func(s Summary) string { return s.Total().String() }},
+}
@@ -0,0 +1,84 @@
+package testnaka
+
+import (
+	"testing"
+
+	"github.com/TN-INCORPORATION/kit/v2/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	body := Body{ReqBody: []Transaction{
+		{
+			TransactionDate: mustDate(t, "2025-01-15"),
+			AccountNumber:   null.NewInt64(1),
+			EventCode:       null.NewString("due_bills"),
+			Message:         null.NewString(`{"principal_amount":0.10,"interest_amount":0.20,"penalty_amount":0.00,"vat_amount":0.01}`),
+		},
+		{
+			TransactionDate: mustDate(t, "2025-01-15"),
+			AccountNumber:   null.NewInt64(1),
+			EventCode:       null.NewString("due_bills"),
+			Message:         null.NewString(`{"principal_amount":0.20,"interest_amount":0.10,"penalty_amount":0.00,"vat_amount":0.02}`),
+		},
+		{
+			TransactionDate: mustDate(t, "2025-01-16"),
+			AccountNumber:   null.NewInt64(1),
+			EventCode:       null.NewString("fee"),
+			Message:         null.NewString(`{"fee_amount":100.00}`),
+		},
+		{
+			TransactionDate: mustDate(t, "2025-01-15"),
+			AccountNumber:   null.NewInt64(2),
+			EventCode:       null.NewString("others"),
+			Message:         null.NewString(`{"principal_amount":7032.09,"interest_amount":2091.91,"penalty_amount":0.01,"other_properties":{"advance_payment":"{\"principal_amount\":7032.09,\"interest_amount\":2091.91,\"penalty_amount\":0.01}"}}`),
+		},
+	}}
+
+	res := Summarize(body, Options{}, GroupAccountDate)
+	assert.Empty(t, res.Errors)
+	if assert.Len(t, res.Summaries, 3) {
+		s := res.Summaries[0]
+		assert.Equal(t, 2, s.Transactions)
+		assert.Equal(t, "0.30", s.PrincipalAmount.String())
+		assert.Equal(t, "0.30", s.InterestAmount.String())
+		assert.Equal(t, "0.03", s.VatAmount.String())
+		assert.Equal(t, "0.63", s.Total().String())
+		assert.Equal(t, "2025-01-16", res.Summaries[1].TransactionDate.String())
+		assert.Equal(t, "100.00", res.Summaries[1].FeeAmount.String())
+		assert.Equal(t, "9124.01", res.Summaries[2].AdvancePaymentAmount.String())
+		// advance_payment breaks the message amounts down, it is not added
+		assert.Equal(t, "9124.01", res.Summaries[2].Total().String())
+	}
+
+	res = Summarize(body, Options{}, GroupAccount)
+	if assert.Len(t, res.Summaries, 2) {
+		assert.Equal(t, 3, res.Summaries[0].Transactions)
+		assert.True(t, res.Summaries[0].TransactionDate.Null())
+	}
+
+	res = Summarize(body, Options{}, GroupDate)
+	if assert.Len(t, res.Summaries, 2) {
+		assert.True(t, res.Summaries[0].AccountNumber.Null())
+		assert.Equal(t, 3, res.Summaries[0].Transactions)
+	}
+}
+
+func TestSummarize_fixture(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	res := Summarize(body, DefaultOptions(), GroupAccount)
+	assert.Empty(t, res.Errors)
+	n := 0
+	for _, s := range res.Summaries {
+		n += s.Transactions
+	}
+	assert.Equal(t, 252, n)
+
+	for _, s := range res.Summaries {
+		if s.AccountNumber.Val == 290000002885 {
+			assert.Equal(t, "9124.00", s.AdvancePaymentAmount.String())
+			assert.Equal(t, "9124.00", s.Total().String())
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package testnaka
+
+import (
+	"io"
+	"sort"
+
+	"github.com/TN-INCORPORATION/kit/v2/decimal"
+	"github.com/TN-INCORPORATION/kit/v2/null"
+)
+
+// TimelineEntry is one event of an account with the amounts paid so far
+type TimelineEntry struct {
+	AccountNumber          null.Int64
+	ChronoSequence         null.String
+	AccountSequence        null.Int64
+	EventCode              null.String
+	TransactionDate        null.Date
+	LastUpdatedDescription null.String
+	// Amounts of this event
+	PrincipalAmount null.Dec2
+	InterestAmount  null.Dec2
+	PenaltyAmount   null.Dec2
+	VatAmount       null.Dec2
+	FeeAmount       null.Dec2
+	// Running totals of the account up to and including this event
+	PaidPrincipal decimal.Dec2
+	PaidInterest  decimal.Dec2
+	PaidPenalty   decimal.Dec2
+	PaidVat       decimal.Dec2
+	PaidFee       decimal.Dec2
+}
+
+// PaidTotal is the sum of the running totals
+func (e TimelineEntry) PaidTotal() decimal.Dec2 {
+	return e.PaidPrincipal.Add(e.PaidInterest).Add(e.PaidPenalty).Add(e.PaidVat).Add(e.PaidFee)
+}
+
+// Timeline is the events of one account in the order they happened
+type Timeline struct {
+	AccountNumber null.Int64
+	Entries       []TimelineEntry
+}
+
+// TimelineResult holds the timelines ordered by account number
+type TimelineResult struct {
+	Timelines []Timeline
+	Errors    []TransactionError
+}
+
+// Entries returns the entries of every timeline, account after account
+func (res TimelineResult) Entries() []TimelineEntry {
+	var entries []TimelineEntry
+	for _, t := range res.Timelines {
+		entries = append(entries, t.Entries...)
+	}
+	return entries
+}
+
+// BuildTimelines reconstructs the timeline of every account of body
+func BuildTimelines(body Body, opts Options) TimelineResult {
+	b := newTimelineBuilder(opts)
+	errs := eachTransaction(body, opts, b.add)
+	return b.result(errs)
+}
+
+// ReportTimeline reconstructs the account timelines of the response read from
+// r and writes them to w
+func ReportTimeline(r io.Reader, w io.Writer, opts Options) (TimelineResult, error) {
+	b := newTimelineBuilder(opts)
+	errs, err := Each(r, opts, b.add)
+	res := b.result(errs)
+	if err != nil {
+		return res, err
+	}
+	return res, WriteTable(w, opts.Output, TimelineColumns, res.Entries())
+}
+
+// timelineBuilder collects entries per account, totals are summed once
+// they are ordered
+type timelineBuilder struct {
+	reg      *Registry
+	accounts map[int64]*Timeline
+}
+
+func newTimelineBuilder(opts Options) *timelineBuilder {
+	return &timelineBuilder{reg: opts.registry(), accounts: map[int64]*Timeline{}}
+}
+
+func (b *timelineBuilder) add(tx Transaction) error {
+	row, err := b.reg.ProcessTransaction(tx)
+	if err != nil {
+		return err
+	}
+	t, ok := b.accounts[tx.AccountNumber.Val]
+	if !ok {
+		t = &Timeline{AccountNumber: tx.AccountNumber}
+		b.accounts[tx.AccountNumber.Val] = t
+	}
+	t.Entries = append(t.Entries, TimelineEntry{
+		AccountNumber:          tx.AccountNumber,
+		ChronoSequence:         tx.ChronoSequence,
+		AccountSequence:        tx.AccountSequence,
+		EventCode:              tx.EventCode,
+		TransactionDate:        tx.TransactionDate,
+		LastUpdatedDescription: tx.LastUpdatedDescription,
+		PrincipalAmount:        row.PrincipalAmount,
+		InterestAmount:         row.InterestAmount,
+		PenaltyAmount:          row.PenaltyAmount,
+		VatAmount:              row.VatAmount,
+		FeeAmount:              row.FeeAmount,
+	})
+	return nil
+}
+
+func (b *timelineBuilder) result(errs []TransactionError) TimelineResult {
+	res := TimelineResult{Errors: errs}
+	for _, t := range b.accounts {
+		sortTimeline(t.Entries)
+		var paid TimelineEntry
+		for i := range t.Entries {
+			e := &t.Entries[i]
+			paid.PaidPrincipal = paid.PaidPrincipal.Add(e.PrincipalAmount.Val)
+			paid.PaidInterest = paid.PaidInterest.Add(e.InterestAmount.Val)
+			paid.PaidPenalty = paid.PaidPenalty.Add(e.PenaltyAmount.Val)
+			paid.PaidVat = paid.PaidVat.Add(e.VatAmount.Val)
+			paid.PaidFee = paid.PaidFee.Add(e.FeeAmount.Val)
+			e.PaidPrincipal = paid.PaidPrincipal
+			e.PaidInterest = paid.PaidInterest
+			e.PaidPenalty = paid.PaidPenalty
+			e.PaidVat = paid.PaidVat
+			e.PaidFee = paid.PaidFee
+		}
+		res.Timelines = append(res.Timelines, *t)
+	}
+	sort.Slice(res.Timelines, func(i, j int) bool {
+		return res.Timelines[i].AccountNumber.Val < res.Timelines[j].AccountNumber.Val
+	})
+	return res
+}
+
+// sortTimeline orders entries by chrono_sequence then account_sequence.
+// chrono_sequence is fixed width so it sorts as a string.
+func sortTimeline(entries []TimelineEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.ChronoSequence.String() != b.ChronoSequence.String() {
+			return a.ChronoSequence.String() < b.ChronoSequence.String()
+		}
+		return a.AccountSequence.Val < b.AccountSequence.Val
+	})
+}
+
+// TimelineColumns are the columns of the timeline report
+var TimelineColumns = []Column[TimelineEntry]{
+	{Name: "AccountNumber", Width: 12, Numeric: true, Value: func(e TimelineEntry) string { return int64Cell(e.AccountNumber) }},
+	{Name: "ChronoSequence", Width: 25, Value: func(e TimelineEntry) string { return stringCell(e.ChronoSequence) }},
+	{Name: "AccountSequence", Width: 8, Numeric: true, Value: func(e TimelineEntry) string { return int64Cell(e.AccountSequence) }},
+	{Name: "TransactionDate", Width: 10, Value: func(e TimelineEntry) string { return dateCell(e.TransactionDate) }},
+	{Name: "EventCode", Width: 10, Value: func(e TimelineEntry) string { return stringCell(e.EventCode) }},
+	{Name: "PrincipalAmount", Width: 15, Numeric: true, Value: func(e TimelineEntry) string { return dec2Cell(e.PrincipalAmount) }},
+	{Name: "InterestAmount", Width: 14, Numeric: true, Value: func(e TimelineEntry) string { return dec2Cell(e.InterestAmount) }},
+	{Name: "PenaltyAmount", Width: 13, Numeric: true, Value: func(e TimelineEntry) string { return dec2Cell(e.PenaltyAmount) }},
+	{Name: "VatAmount", Width: 12, Numeric: true, Value: func(e TimelineEntry) string { return dec2Cell(e.VatAmount) }},
+	{Name: "FeeAmount", Width: 12, Numeric: true, Value: func(e TimelineEntry) string { return dec2Cell(e.FeeAmount) }},
+	{Name: "PaidPrincipal", Width: 15, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidPrincipal.String() }},
+	{Name: "PaidInterest", Width: 14, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidInterest.String() }},
+	{Name: "PaidPenalty", Width: 13, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidPenalty.String() }},
+	{Name: "PaidVat", Width: 12, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidVat.String() }},
+	{Name: "PaidFee", Width: 12, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidFee.String() }},
+	{Name: "PaidTotal", Width: 15, Numeric: true, Value: func(e TimelineEntry) string { return e.PaidTotal().String() }},
+	{Name: "Entry", Width: 80, Value: func(e TimelineEntry) string { return stringCell(e.LastUpdatedDescription) }},
+}
@@ -0,0 +1,76 @@
+package testnaka
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportTimeline(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Filter.AccountNumbers = []int64{190000069047}
+	var out bytes.Buffer
+	res, err := ReportTimeline(openFixture(t), &out, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Errors)
+	if !assert.Len(t, res.Timelines, 1) {
+		return
+	}
+	entries := res.Timelines[0].Entries
+	assert.Len(t, entries, 11)
+	assert.Equal(t, "fee", entries[0].EventCode.String())
+	assert.Equal(t, "800.00", entries[0].PaidFee.String())
+
+	last := entries[len(entries)-1]
+	assert.Equal(t, int64(-101), last.AccountSequence.Val)
+	assert.Equal(t, "5708.94", last.PaidPrincipal.String())
+	assert.Equal(t, "2889.79", last.PaidPenalty.String())
+	assert.Equal(t, "800.00", last.PaidFee.String())
+	assert.Equal(t, "9852.79", last.PaidTotal().String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 12)
+	assert.True(t, strings.HasPrefix(lines[1], "190000069047|250115110204477938089344A|1|2025-01-15|fee|"))
+}
+
+func TestBuildTimelines_order(t *testing.T) {
+	body, err := DecodeBody(openFixture(t))
+	assert.NoError(t, err)
+	res := BuildTimelines(body, DefaultOptions())
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, 89, len(res.Timelines))
+
+	var n int
+	for i, tl := range res.Timelines {
+		if i > 0 {
+			assert.Less(t, res.Timelines[i-1].AccountNumber.Val, tl.AccountNumber.Val)
+		}
+		for j, e := range tl.Entries {
+			assert.Equal(t, tl.AccountNumber, e.AccountNumber)
+			if j > 0 {
+				assert.LessOrEqual(t, tl.Entries[j-1].ChronoSequence.String(), e.ChronoSequence.String())
+			}
+		}
+		n += len(tl.Entries)
+	}
+	assert.Equal(t, 252, n)
+}
+
+func TestSortTimeline_tieBreak(t *testing.T) {
+	in := `{"rs_body":[
+		{"account_number":1,"account_sequence":3,"chrono_sequence":"B","event_code":"fee","message":"{\"fee_amount\":1}"},
+		{"account_number":1,"account_sequence":2,"chrono_sequence":"A","event_code":"fee","message":"{\"fee_amount\":2}"},
+		{"account_number":1,"account_sequence":-1,"chrono_sequence":"A","event_code":"fee","message":"{\"fee_amount\":4}"}
+	]}`
+	res, err := ReportTimeline(strings.NewReader(in), &bytes.Buffer{}, Options{})
+	assert.NoError(t, err)
+	var seqs, paid []string
+	for _, e := range res.Entries() {
+		seqs = append(seqs, e.AccountSequence.String())
+		paid = append(paid, e.PaidFee.String())
+	}
+	assert.Equal(t, []string{"-1", "2", "3"}, seqs)
+	assert.Equal(t, []string{"4.00", "6.00", "7.00"}, paid)
+}